@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// prefix of the api-tokens, so they can be recognized in scripts and secret-scanners
+const apiTokenPrefix = "pvp_"
+
+// maximum length of the name of an api-token
+const apiTokenNameLength = 64
+
+// available scopes of the api-tokens
+const (
+	scopeReadSponsorships  = "read:sponsorships"
+	scopeWriteReservations = "write:reservations"
+	scopeWriteSponsorships = "write:sponsorships"
+	scopeManageUsers       = "manage:users"
+	scopeManageOutbox      = "manage:outbox"
+	scopeWritePayments     = "write:payments"
+)
+
+// permissions granted by the individual scopes, the account-endpoints are only accessible with a session
+var scopePermissions = map[string][]Permission{
+	scopeReadSponsorships: {
+		permissionReadElements,
+	},
+	scopeWriteReservations: {
+		permissionEditElements,
+		permissionConfirmReservations,
+	},
+	scopeWriteSponsorships: {
+		permissionEditElements,
+		permissionDeleteSponsorships,
+	},
+	scopeManageUsers: {
+		permissionManageUsers,
+	},
+	scopeManageOutbox: {
+		permissionManageOutbox,
+	},
+	scopeWritePayments: {
+		permissionManagePayments,
+	},
+}
+
+// checks wether one of the scopes grants a permission
+func scopesGrant(scopes []string, permission Permission) bool {
+	if permission == permissionPublic {
+		return true
+	}
+
+	for _, scope := range scopes {
+		if slices.Contains(scopePermissions[scope], permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checks wether a role grants all permissions of a scope
+func scopeGrantedTo(scope, role string) bool {
+	if permissions, ok := scopePermissions[scope]; !ok {
+		return false
+	} else {
+		for _, permission := range permissions {
+			if !permission.grantedTo(role) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// api-token in the database
+type APITokenDB struct {
+	Id      string `json:"id"`
+	Uid     int    `json:"uid"`
+	Name    string `json:"name"`
+	Hash    string `json:"-"`
+	Scopes  string `json:"scopes"`
+	Created int64  `json:"created"`
+	Expires *int64 `json:"expires"`
+	Used    int64  `json:"used"`
+	Ip      string `json:"ip"`
+}
+
+// checks wether an api-token is expired at the given time
+func (token APITokenDB) expired(now time.Time) bool {
+	return token.Expires != nil && *token.Expires <= now.Unix()
+}
+
+// hashes an api-token for storing it in the database
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// returns the api-token from the authorization-header
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	const scheme = "Bearer "
+
+	if header := c.Get(fiber.HeaderAuthorization); len(header) > len(scheme) && strings.EqualFold(header[:len(scheme)], scheme) {
+		return strings.TrimSpace(header[len(scheme):]), true
+	} else {
+		return "", false
+	}
+}
+
+// checks the api-token of a request and returns the session of its user, nil if it isn't valid
+func checkAPIToken(c *fiber.Ctx, token string) (*JWTPayload, error) {
+	now := time.Now()
+
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, nil
+	}
+
+	if apiToken, err := store.GetAPIToken(hashAPIToken(token)); err != nil || apiToken == nil {
+		return nil, err
+	} else if apiToken.expired(now) {
+		logger.Info().Msgf("expired api-token %q of user with uid = %d was used", apiToken.Id, apiToken.Uid)
+
+		return nil, nil
+	} else if user, err := store.GetUser(apiToken.Uid); err != nil || user == nil {
+		return nil, err
+	} else if err := store.TouchAPIToken(apiToken.Id, c.IP(), now, sessionTouchInterval); err != nil {
+		return nil, err
+	} else {
+		// the role is taken from the user, so role-changes apply immediately
+		return &JWTPayload{
+			Uid:    user.Uid,
+			Tid:    user.Tid,
+			Role:   user.Role,
+			Token:  apiToken.Id,
+			Scopes: strings.Split(apiToken.Scopes, ","),
+		}, nil
+	}
+}
+
+// api-token sent to the client after its creation, the only time the token itself is visible
+type CreatedAPITokenData struct {
+	APITokenDB
+	Token string `json:"token"`
+}
+
+// handles get-requests for the api-tokens of the user
+func getTokens(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	uid := getSession(c).Uid
+
+	if tokens, err := store.GetAPITokens(uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get api-tokens of user with uid = %d from database: %v", uid, err)
+	} else {
+		response.Data = tokens
+	}
+
+	return response
+}
+
+// handles post-requests to create an api-token
+func postTokens(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	session := getSession(c)
+	now := time.Now()
+
+	body := struct {
+		Name    string   `json:"name"`
+		Scopes  []string `json:"scopes"`
+		Expires *int64   `json:"expires"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse api-token-body: %v", err)
+	} else if body.Name = strings.TrimSpace(body.Name); body.Name == "" || len(body.Name) > apiTokenNameLength {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid name"
+
+		logger.Info().Msgf("invalid api-token-name %q", body.Name)
+	} else if len(body.Scopes) == 0 || slices.ContainsFunc(body.Scopes, func(scope string) bool { return !scopeGrantedTo(scope, session.Role) }) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid scopes"
+
+		logger.Info().Msgf("user with uid = %d requested invalid scopes %q", session.Uid, body.Scopes)
+	} else if body.Expires != nil && *body.Expires <= now.Unix() {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "expiration is in the past"
+
+		logger.Info().Msg("api-token-expiration is in the past")
+	} else if id, err := randomToken(8); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create api-token-id: %v", err)
+	} else if secret, err := randomToken(32); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create api-token: %v", err)
+	} else {
+		slices.Sort(body.Scopes)
+
+		token := apiTokenPrefix + secret
+
+		apiToken := APITokenDB{
+			Id:      id,
+			Uid:     session.Uid,
+			Name:    body.Name,
+			Hash:    hashAPIToken(token),
+			Scopes:  strings.Join(slices.Compact(body.Scopes), ","),
+			Created: now.Unix(),
+			Expires: body.Expires,
+		}
+
+		if err := store.AddAPIToken(apiToken); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't write api-token to database: %v", err)
+		} else {
+			response.Data = CreatedAPITokenData{
+				APITokenDB: apiToken,
+				Token:      token,
+			}
+
+			logger.Info().Msgf("user with uid = %d created api-token %q with scopes %q", session.Uid, apiToken.Id, apiToken.Scopes)
+		}
+	}
+
+	return response
+}
+
+// handles delete-requests to revoke an api-token of the user
+func deleteTokens(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	uid := getSession(c).Uid
+
+	if id := c.Query("id"); id == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if ok, err := store.DeleteAPIToken(id, uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't remove api-token of user with uid = %d: %v", uid, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "api-token doesn't exist"
+
+		logger.Info().Msgf("user with uid = %d tried to revoke unknown api-token", uid)
+	} else {
+		logger.Info().Msgf("user with uid = %d revoked api-token %q", uid, id)
+
+		response = getTokens(c)
+	}
+
+	return response
+}
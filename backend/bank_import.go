@@ -0,0 +1,657 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// states of the imported bank-transactions
+const (
+	transactionOpen      = "open"
+	transactionApproved  = "approved"
+	transactionDismissed = "dismissed"
+)
+
+// reasons for proposing an element for a bank-transaction
+const (
+	matchReference = "reference"
+	matchAmount    = "amount"
+	matchName      = "name"
+)
+
+// weights of the match-reasons, an element is proposed with the reference or with amount and name
+var matchScores = map[string]int{
+	matchReference: 4,
+	matchAmount:    2,
+	matchName:      1,
+}
+
+// minimum score for proposing an element
+const matchThreshold = 3
+
+// maximum length of the counterparty-name in the database
+const transactionNameLength = 255
+
+// transaction from a bank-statement, amounts in cents and negative for debits
+type BankTransaction struct {
+	Booked  string
+	Amount  int64
+	Name    string
+	IBAN    string
+	Purpose string
+	// reference of the bank for the transaction, if available
+	Reference string
+}
+
+// new imported bank-transaction for the database
+type TransactionEntryDB struct {
+	Hash    string
+	Booked  string
+	Amount  int64
+	Name    string
+	Iban    string
+	Purpose string
+	Mid     *string
+	Reasons string
+	Status  string
+	Created int64
+}
+
+// imported bank-transaction with the proposed element
+type TransactionDB struct {
+	Id      int     `json:"id"`
+	Hash    string  `json:"-"`
+	Booked  string  `json:"booked"`
+	Amount  int64   `json:"amount"`
+	Name    string  `json:"name"`
+	Iban    string  `json:"iban"`
+	Purpose string  `json:"purpose"`
+	Mid     *string `json:"mid"`
+	Reasons string  `json:"reasons"`
+	Status  string  `json:"status"`
+	Created int64   `json:"created"`
+}
+
+// parses a bank-statement as camt.053-xml or as csv-export of a german bank
+func parseBankStatement(content string) ([]BankTransaction, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+
+	if strings.HasPrefix(strings.TrimSpace(content), "<") {
+		return parseCAMT053(content)
+	} else {
+		return parseBankCSV(content)
+	}
+}
+
+// amount with currency in a camt-statement
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// details of a single transaction in a camt-entry
+type camtDetails struct {
+	Amount   camtAmount `xml:"Amt"`
+	TxAmount camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	// the debtor-name moved into "Pty" with newer versions
+	DebtorName      string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPartyName string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	DebtorIBAN      string   `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Unstructured    []string `xml:"RmtInf>Ustrd"`
+	References      []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+}
+
+// booking-entry of a camt-statement
+type camtEntry struct {
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	// the status is a code-element with newer versions
+	Status struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate     string        `xml:"BookgDt>Dt"`
+	BookingDateTime string        `xml:"BookgDt>DtTm"`
+	Reference       string        `xml:"AcctSvcrRef"`
+	Details         []camtDetails `xml:"NtryDtls>TxDtls"`
+}
+
+// iso-20022 bank-to-customer-statement
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// parses the booked entries of a camt.053-statement
+func parseCAMT053(content string) ([]BankTransaction, error) {
+	var document camtDocument
+
+	decoder := xml.NewDecoder(strings.NewReader(content))
+
+	// the content is already decoded by the client, regardless of the declared encoding
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("can't parse camt-statement: %v", err)
+	}
+
+	transactions := []BankTransaction{}
+
+	for _, statement := range document.Statements {
+		for _, entry := range statement.Entries {
+			// skip pending entries
+			if status := strings.TrimSpace(cmp.Or(entry.Status.Code, entry.Status.Value)); status != "" && status != "BOOK" {
+				continue
+			}
+
+			booked := entry.BookingDate
+			if booked == "" && len(entry.BookingDateTime) >= len(time.DateOnly) {
+				booked = entry.BookingDateTime[:len(time.DateOnly)]
+			}
+
+			if !validatePaymentDate(booked) {
+				return nil, fmt.Errorf("invalid booking-date %q", booked)
+			}
+
+			details := entry.Details
+
+			// entries without details are a single transaction
+			if len(details) == 0 {
+				details = []camtDetails{{}}
+			}
+
+			for _, detail := range details {
+				amount := entry.Amount
+
+				// batch-entries carry the amounts in the details
+				if len(details) > 1 {
+					amount = detail.Amount
+
+					if amount.Value == "" {
+						amount = detail.TxAmount
+					}
+				}
+
+				if amount.Currency != "" && amount.Currency != "EUR" {
+					continue
+				}
+
+				cents, err := parseCents(amount.Value, '.')
+				if err != nil {
+					return nil, err
+				}
+
+				if entry.Indicator == "DBIT" {
+					cents = -cents
+				}
+
+				transactions = append(transactions, BankTransaction{
+					Booked:    booked,
+					Amount:    cents,
+					Name:      strings.TrimSpace(cmp.Or(detail.DebtorName, detail.DebtorPartyName)),
+					IBAN:      strings.TrimSpace(detail.DebtorIBAN),
+					Purpose:   strings.TrimSpace(strings.Join(append(detail.References, detail.Unstructured...), " ")),
+					Reference: entry.Reference,
+				})
+			}
+		}
+	}
+
+	return transactions, nil
+}
+
+// column-headers of the german bank-exports, normalized and in order of preference
+var csvColumns = map[string][]string{
+	"date":    {"buchungstag", "buchungsdatum", "buchung", "datum"},
+	"amount":  {"betrag", "betrag (eur)", "betrag (€)", "betrag in eur", "umsatz"},
+	"name":    {"beguenstigter/zahlungspflichtiger", "name zahlungsbeteiligter", "zahlungspflichtige*r", "auftraggeber/empfaenger", "auftraggeber / beguenstigter", "empfaenger/auftraggeber", "name"},
+	"iban":    {"kontonummer/iban", "iban zahlungsbeteiligter", "iban auftraggeber", "iban"},
+	"purpose": {"verwendungszweck", "buchungstext", "vorgang/verwendungszweck"},
+	"sign":    {"soll/haben", "s/h"},
+}
+
+// normalizes a csv-header for comparing it with the known columns
+func normalizeCSVHeader(header string) string {
+	return strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss").Replace(strings.ToLower(strings.TrimSpace(header)))
+}
+
+// parses the csv-export of a german bank, the header-row is searched as some banks put account-information above it
+func parseBankCSV(content string) ([]BankTransaction, error) {
+	// use the most frequent separator of the first lines
+	sample := content[:min(len(content), 4096)]
+	separator := ';'
+
+	for _, candidate := range []rune{',', '\t'} {
+		if strings.Count(sample, string(candidate)) > strings.Count(sample, string(separator)) {
+			separator = candidate
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("can't parse csv-statement: %v", err)
+	}
+
+	for ii, record := range records {
+		columns := map[string]int{}
+
+		for column, candidates := range csvColumns {
+			for _, candidate := range candidates {
+				if index := slices.IndexFunc(record, func(header string) bool { return normalizeCSVHeader(header) == candidate }); index >= 0 {
+					columns[column] = index
+
+					break
+				}
+			}
+		}
+
+		if _, ok := columns["date"]; !ok {
+			continue
+		} else if _, ok := columns["amount"]; !ok {
+			continue
+		}
+
+		return parseBankCSVRows(records[ii+1:], columns)
+	}
+
+	return nil, fmt.Errorf("can't find the header-row of the csv-statement")
+}
+
+// parses the rows of a csv-statement with the found columns
+func parseBankCSVRows(records [][]string, columns map[string]int) ([]BankTransaction, error) {
+	transactions := []BankTransaction{}
+
+	field := func(record []string, column string) string {
+		if index, ok := columns[column]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		} else {
+			return ""
+		}
+	}
+
+	for _, record := range records {
+		// skip rows without a booking-date, e.g. the closing balance
+		booked, ok := parseCSVDate(field(record, "date"))
+		if !ok {
+			continue
+		}
+
+		amount, err := parseCents(field(record, "amount"), ',')
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.EqualFold(field(record, "sign"), "S") {
+			amount = -amount
+		}
+
+		transactions = append(transactions, BankTransaction{
+			Booked:  booked,
+			Amount:  amount,
+			Name:    field(record, "name"),
+			IBAN:    strings.ReplaceAll(field(record, "iban"), " ", ""),
+			Purpose: field(record, "purpose"),
+		})
+	}
+
+	return transactions, nil
+}
+
+// parses the german date-formats of the csv-exports into "2006-01-02"
+func parseCSVDate(date string) (string, bool) {
+	for _, layout := range []string{"02.01.2006", "02.01.06", time.DateOnly} {
+		if parsed, err := time.Parse(layout, date); err == nil {
+			return parsed.Format(time.DateOnly), true
+		}
+	}
+
+	return "", false
+}
+
+// parses a decimal amount into cents, e.g. "-1.250,50 €" with the decimal-separator ','
+func parseCents(amount string, decimal byte) (int64, error) {
+	value := strings.TrimSpace(strings.NewReplacer("€", "", "EUR", "", " ", "", "\u00a0", "").Replace(amount))
+
+	negative := false
+
+	if strings.HasPrefix(value, "-") || strings.HasSuffix(value, "-") {
+		negative = true
+		value = strings.Trim(value, "-")
+	} else {
+		value = strings.TrimPrefix(value, "+")
+	}
+
+	thousands := "."
+	if decimal == '.' {
+		thousands = ","
+	}
+
+	euros, cents, _ := strings.Cut(value, string(decimal))
+
+	if len(cents) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	// remove the thousands-separators, a separator in any other position means the amount uses the other decimal-separator
+	groups := strings.Split(euros, thousands)
+
+	for ii, group := range groups {
+		if len(groups) > 1 && (ii == 0 && (len(group) == 0 || len(group) > 3) || ii > 0 && len(group) != 3) {
+			return 0, fmt.Errorf("invalid amount %q", amount)
+		}
+	}
+
+	euros = strings.Join(groups, "")
+
+	cents = (cents + "00")[:2]
+
+	if result, err := strconv.ParseUint(cmp.Or(euros, "0")+cents, 10, 63); err != nil {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	} else if negative {
+		return -int64(result), nil
+	} else {
+		return int64(result), nil
+	}
+}
+
+// removes spaces and dashes and uppercases a text for searching references in it
+func normalizeReference(text string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "\n", "", "\t", "").Replace(text))
+}
+
+// proposes the reservation for a credit-transaction by reference, amount and name,
+// returns nil if none or more than one reservation is a match
+func matchTransaction(transaction BankTransaction, reservations []ElementDB) (*string, []string) {
+	purpose := normalizeReference(transaction.Purpose)
+	text := strings.ToLower(transaction.Name + " " + transaction.Purpose)
+
+	var match *string
+	var matchReasons []string
+	best := 0
+	ambiguous := false
+
+	for _, reservation := range reservations {
+		var reasons []string
+
+		if reservation.Remittance != nil && strings.Contains(purpose, normalizeReference(*reservation.Remittance)) {
+			reasons = append(reasons, matchReference)
+		}
+
+		if transaction.Amount == amountDue(reservation.Mid, reservation.Due)-reservation.Received {
+			reasons = append(reasons, matchAmount)
+		}
+
+		// every part of the sponsor-name has to be included in the name or purpose of the transaction
+		if words := strings.Fields(strings.ToLower(reservation.Name)); len(words) > 0 && !slices.ContainsFunc(words, func(word string) bool { return !strings.Contains(text, word) }) {
+			reasons = append(reasons, matchName)
+		}
+
+		score := 0
+		for _, reason := range reasons {
+			score += matchScores[reason]
+		}
+
+		if score < matchThreshold || score < best {
+			continue
+		} else if score == best {
+			ambiguous = true
+		} else {
+			mid := reservation.Mid
+
+			match = &mid
+			matchReasons = reasons
+			best = score
+			ambiguous = false
+		}
+	}
+
+	if ambiguous {
+		return nil, nil
+	} else {
+		return match, matchReasons
+	}
+}
+
+// identifies a transaction for skipping it on repeated imports, identical transactions in a statement are distinguished by their occurrence
+func transactionHash(transaction BankTransaction, occurrence int) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%s|%s|%s|%s|%d", transaction.Booked, transaction.Amount, transaction.Name, transaction.IBAN, transaction.Purpose, transaction.Reference, occurrence))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// responds with the open bank-transactions
+func openTransactions() responseMessage {
+	var response responseMessage
+
+	if transactions, err := store.GetTransactions(transactionOpen); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get transactions from database: %v", err)
+	} else {
+		response.Data = transactions
+	}
+
+	return response
+}
+
+// handles get-requests for the imported bank-transactions, optionally filtered by their status
+func getTransactions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if status := c.Query("status"); status != "" && status != transactionOpen && status != transactionApproved && status != transactionDismissed {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid status"
+
+		logger.Info().Msgf("invalid transaction-status %q", status)
+	} else if transactions, err := store.GetTransactions(status); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get transactions from database: %v", err)
+	} else {
+		response.Data = transactions
+	}
+
+	return response
+}
+
+// handles post-requests to import a bank-statement and propose the matching reservations for its credits
+func postTransactions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Content string `json:"content"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse statement-body: %v", err)
+	} else if transactions, err := parseBankStatement(body.Content); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid bank-statement"
+
+		logger.Info().Msgf("can't parse bank-statement: %v", err)
+	} else if reservations, err := store.GetReservations(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get reservations from database: %v", err)
+	} else {
+		// only reservations with an open amount are proposed
+		reservations = slices.DeleteFunc(reservations, func(reservation ElementDB) bool {
+			return reservation.Received >= amountDue(reservation.Mid, reservation.Due)
+		})
+
+		occurrences := map[string]int{}
+		imported := 0
+
+		for _, transaction := range transactions {
+			// only credits can be payments of sponsors
+			if transaction.Amount <= 0 {
+				continue
+			}
+
+			mid, reasons := matchTransaction(transaction, reservations)
+
+			hash := transactionHash(transaction, 0)
+			occurrences[hash]++
+
+			if ok, err := store.AddTransaction(TransactionEntryDB{
+				Hash:    transactionHash(transaction, occurrences[hash]),
+				Booked:  transaction.Booked,
+				Amount:  transaction.Amount,
+				Name:    truncateString(transaction.Name, transactionNameLength),
+				Iban:    transaction.IBAN,
+				Purpose: transaction.Purpose,
+				Mid:     mid,
+				Reasons: strings.Join(reasons, ","),
+				Status:  transactionOpen,
+				Created: time.Now().Unix(),
+			}); err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't write transaction to database: %v", err)
+
+				return response
+			} else if ok {
+				imported++
+			}
+		}
+
+		logger.Info().Msgf("user with uid = %d imported %d new transactions from a bank-statement with %d transactions", getSession(c).Uid, imported, len(transactions))
+
+		response = openTransactions()
+	}
+
+	return response
+}
+
+// handles patch-requests to approve a bank-transaction as payment for a reservation, optionally confirming the sponsorship once it is fully paid
+func patchTransactions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Mid         string `json:"mid"`
+		Certificate bool   `json:"certificate"`
+	}{}
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse transaction-body: %v", err)
+	} else if !isValidMid(body.Mid) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid mID"
+
+		logger.Info().Msgf("can't approve transaction: invalid element-name: %q", body.Mid)
+	} else if body.Certificate && !sessionGrants(c, permissionConfirmReservations) {
+		response.Status = fiber.StatusForbidden
+
+		logger.Info().Msgf("user with uid = %d isn't allowed to confirm reservations", getSession(c).Uid)
+	} else if transaction, err := store.GetTransaction(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get transaction %d from database: %v", id, err)
+	} else if transaction == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "transaction doesn't exist"
+
+		logger.Info().Msgf("transaction %d doesn't exist", id)
+	} else if ok, err := store.UpdateTransaction(id, transactionOpen, transactionApproved, &body.Mid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't approve transaction %d: %v", id, err)
+	} else if !ok {
+		response.Status = fiber.StatusConflict
+		response.Message = "transaction is already processed"
+
+		logger.Info().Msgf("transaction %d is already processed", id)
+	} else if ok, err := store.RecordPayment(body.Mid, transaction.Amount, transaction.Booked, paymentTransfer, optionalString(truncateString(transaction.Purpose, paymentReferenceLength))); err != nil || !ok {
+		if err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't record payment of transaction %d for %q: %v", id, body.Mid, err)
+		} else {
+			response.Status = fiber.StatusNotFound
+			response.Message = "no reservation found"
+
+			logger.Info().Msgf("no element-reservation for %q", body.Mid)
+		}
+
+		// reopen the transaction, since the payment wasn't recorded
+		if _, err := store.ReopenTransaction(id, transaction.Mid); err != nil {
+			logger.Error().Msgf("can't reopen transaction %d: %v", id, err)
+		}
+	} else {
+		logger.Info().Msgf("user with uid = %d approved transaction %d as payment of %d cents for %q", getSession(c).Uid, id, transaction.Amount, body.Mid)
+
+		// confirm the sponsorship and send the certificate, once the reservation is paid
+		if body.Certificate {
+			if element, err := store.GetElement(body.Mid); err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't retrieve element-data for %q: %v", body.Mid, err)
+			} else if element != nil && element.Confirmation == nil && element.Received >= amountDue(element.Mid, element.Due) {
+				response = confirmSponsorship(*element)
+			} else {
+				logger.Info().Msgf("reservation for %q isn't confirmed or fully paid yet, the certificate isn't sent", body.Mid)
+			}
+		}
+
+		if response.Status < 400 {
+			response = openTransactions()
+		}
+	}
+
+	return response
+}
+
+// handles delete-requests to dismiss a bank-transaction, which isn't a payment of a sponsor
+func deleteTransactions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if ok, err := store.UpdateTransaction(id, transactionOpen, transactionDismissed, nil); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't dismiss transaction %d: %v", id, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "no open transaction found"
+
+		logger.Info().Msgf("no open transaction %d", id)
+	} else {
+		logger.Info().Msgf("user with uid = %d dismissed transaction %d", getSession(c).Uid, id)
+
+		response = openTransactions()
+	}
+
+	return response
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCents(t *testing.T) {
+	tests := []struct {
+		amount  string
+		decimal byte
+		cents   int64
+		valid   bool
+	}{
+		{"1.250,50 €", ',', 125050, true},
+		{"-1.250,50", ',', -125050, true},
+		{"1250,50", ',', 125050, true},
+		{"12,5", ',', 1250, true},
+		{"+3", ',', 300, true},
+		{"1.000.000,00 EUR", ',', 100000000, true},
+		{"1250.50", ',', 0, false},
+		{"1.2", ',', 0, false},
+		{"1250.000", ',', 0, false},
+		{"12,345", ',', 0, false},
+		{"abc", ',', 0, false},
+		{"1250.50", '.', 125050, true},
+		{"1,250.50", '.', 125050, true},
+		{"0.5", '.', 50, true},
+		{"-20", '.', -2000, true},
+		{"1250,50", '.', 0, false},
+		{"12.345", '.', 0, false},
+	}
+
+	for _, test := range tests {
+		cents, err := parseCents(test.amount, test.decimal)
+
+		if !test.valid {
+			if err == nil {
+				t.Errorf("parseCents(%q, %q) = %d, expected an error", test.amount, test.decimal, cents)
+			}
+		} else if err != nil {
+			t.Errorf("parseCents(%q, %q) failed: %v", test.amount, test.decimal, err)
+		} else if cents != test.cents {
+			t.Errorf("parseCents(%q, %q) = %d, expected %d", test.amount, test.decimal, cents, test.cents)
+		}
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	const statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+	<BkToCstmrStmt>
+		<Stmt>
+			<Ntry>
+				<Amt Ccy="EUR">1250.00</Amt>
+				<CdtDbtInd>CRDT</CdtDbtInd>
+				<Sts>BOOK</Sts>
+				<BookgDt><Dt>2024-03-01</Dt></BookgDt>
+				<AcctSvcrRef>REF1</AcctSvcrRef>
+				<NtryDtls>
+					<TxDtls>
+						<RltdPties>
+							<Dbtr><Nm>Erika Mustermann</Nm></Dbtr>
+							<DbtrAcct><Id><IBAN>DE89370400440532013000</IBAN></Id></DbtrAcct>
+						</RltdPties>
+						<RmtInf>
+							<Ustrd>Patenschaft</Ustrd>
+							<Strd><CdtrRefInf><Ref>RF18539007547034</Ref></CdtrRefInf></Strd>
+						</RmtInf>
+					</TxDtls>
+				</NtryDtls>
+			</Ntry>
+			<Ntry>
+				<Amt Ccy="EUR">99.00</Amt>
+				<CdtDbtInd>CRDT</CdtDbtInd>
+				<Sts>PDNG</Sts>
+				<BookgDt><Dt>2024-03-02</Dt></BookgDt>
+			</Ntry>
+			<Ntry>
+				<Amt Ccy="EUR">12.34</Amt>
+				<CdtDbtInd>DBIT</CdtDbtInd>
+				<Sts><Cd>BOOK</Cd></Sts>
+				<BookgDt><DtTm>2024-03-03T10:00:00</DtTm></BookgDt>
+			</Ntry>
+			<Ntry>
+				<Amt Ccy="EUR">1100.00</Amt>
+				<CdtDbtInd>CRDT</CdtDbtInd>
+				<Sts>BOOK</Sts>
+				<BookgDt><Dt>2024-03-04</Dt></BookgDt>
+				<NtryDtls>
+					<TxDtls>
+						<Amt Ccy="EUR">550.00</Amt>
+						<RltdPties><Dbtr><Pty><Nm>Max Mustermann</Nm></Pty></Dbtr></RltdPties>
+					</TxDtls>
+					<TxDtls>
+						<AmtDtls><TxAmt><Amt Ccy="EUR">550.00</Amt></TxAmt></AmtDtls>
+						<RltdPties><Dbtr><Nm>Anna Beispiel</Nm></Dbtr></RltdPties>
+					</TxDtls>
+				</NtryDtls>
+			</Ntry>
+		</Stmt>
+	</BkToCstmrStmt>
+</Document>`
+
+	expected := []BankTransaction{
+		{Booked: "2024-03-01", Amount: 125000, Name: "Erika Mustermann", IBAN: "DE89370400440532013000", Purpose: "RF18539007547034 Patenschaft", Reference: "REF1"},
+		{Booked: "2024-03-03", Amount: -1234},
+		{Booked: "2024-03-04", Amount: 55000, Name: "Max Mustermann"},
+		{Booked: "2024-03-04", Amount: 55000, Name: "Anna Beispiel"},
+	}
+
+	if transactions, err := parseBankStatement(statement); err != nil {
+		t.Fatalf("can't parse camt-statement: %v", err)
+	} else if !slices.Equal(transactions, expected) {
+		t.Errorf("parsed transactions %+v, expected %+v", transactions, expected)
+	}
+
+	if _, err := parseBankStatement(`<Document><BkToCstmrStmt><Stmt><Ntry><Amt>1250,00</Amt><Sts>BOOK</Sts><BookgDt><Dt>2024-03-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>`); err == nil {
+		t.Error("camt-statement with a comma-amount was parsed")
+	}
+}
+
+func TestParseBankCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		expected  []BankTransaction
+		valid     bool
+	}{
+		{
+			name: "header below account-information",
+			statement: "\ufeffKonto;DE89370400440532013000\n" +
+				"\n" +
+				"Buchungstag;Beguenstigter/Zahlungspflichtiger;IBAN;Verwendungszweck;Betrag;Soll/Haben\n" +
+				"01.03.2024;Erika Mustermann;DE89 3704 0044 0532 0130 00;RF18539007547034;1.250,00;H\n" +
+				"03.03.24;Bank;;Kontogebuehr;12,34;S\n" +
+				";Schlusssaldo;;;1.237,66;H\n",
+			expected: []BankTransaction{
+				{Booked: "2024-03-01", Amount: 125000, Name: "Erika Mustermann", IBAN: "DE89370400440532013000", Purpose: "RF18539007547034"},
+				{Booked: "2024-03-03", Amount: -1234, Name: "Bank", Purpose: "Kontogebuehr"},
+			},
+			valid: true,
+		},
+		{
+			name: "comma-separated with signed amounts",
+			statement: "Buchungsdatum,Name Zahlungsbeteiligter,Betrag (€)\n" +
+				"2024-03-05,Max Mustermann,\"550,00\"\n" +
+				"2024-03-06,Stadtwerke,\"-80,00\"\n",
+			expected: []BankTransaction{
+				{Booked: "2024-03-05", Amount: 55000, Name: "Max Mustermann"},
+				{Booked: "2024-03-06", Amount: -8000, Name: "Stadtwerke"},
+			},
+			valid: true,
+		},
+		{
+			name:      "dot-decimal amount",
+			statement: "Buchungstag;Betrag\n01.03.2024;1250.50\n",
+		},
+		{
+			name:      "missing header-row",
+			statement: "Datum der Abfrage;01.03.2024\n",
+		},
+	}
+
+	for _, test := range tests {
+		transactions, err := parseBankStatement(test.statement)
+
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: parsed %+v, expected an error", test.name, transactions)
+			}
+		} else if err != nil {
+			t.Errorf("%s: can't parse csv-statement: %v", test.name, err)
+		} else if !slices.Equal(transactions, test.expected) {
+			t.Errorf("%s: parsed transactions %+v, expected %+v", test.name, transactions, test.expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// articles of the element-names in the accusative by their grammatical gender
+var genderArticles = map[string]struct {
+	Definite      string
+	Demonstrative string
+}{
+	"m": {Definite: "den", Demonstrative: "diesen"},
+	"f": {Definite: "die", Demonstrative: "diese"},
+	"n": {Definite: "das", Demonstrative: "dieses"},
+}
+
+// validates the element-catalogue and maps the ids to the element-types
+func parseElementCatalogue(elementTypesYaml []ElementTypeYaml) (map[string]ElementTypeYaml, error) {
+	elementTypes := map[string]ElementTypeYaml{}
+
+	for _, elementType := range elementTypesYaml {
+		if elementType.ID == "" || elementType.Name == "" {
+			return nil, fmt.Errorf("element-type without id or name")
+		} else if _, ok := elementTypes[elementType.ID]; ok {
+			return nil, fmt.Errorf("duplicate element-type %q", elementType.ID)
+		} else if _, ok := genderArticles[elementType.Gender]; !ok {
+			return nil, fmt.Errorf("invalid gender %q of element-type %q", elementType.Gender, elementType.ID)
+		} else if elementType.Price < 0 {
+			return nil, fmt.Errorf("negative price of element-type %q", elementType.ID)
+		}
+
+		elementTypes[elementType.ID] = elementType
+	}
+
+	return elementTypes, nil
+}
+
+// returns the type of an element, false if the element isn't part of the layout
+func lookupElement(mid string) (ElementTypeYaml, bool) {
+	if typeID, ok := config.Layout.types[mid]; !ok {
+		return ElementTypeYaml{}, false
+	} else {
+		return config.ElementTypes[typeID], true
+	}
+}
+
+// checks wether an element-id is part of the layout
+func isValidMid(mid string) bool {
+	_, ok := lookupElement(mid)
+
+	return ok
+}
+
+// element-type sent to the client
+type ElementTypeData struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Gender      string  `json:"gender"`
+	Description string  `json:"description"`
+	Location    string  `json:"location,omitempty"`
+	Price       float64 `json:"price"`
+	Capacity    string  `json:"capacity,omitempty"`
+	Count       int     `json:"count"`
+}
+
+// handles get-requests for the element-catalogue
+func getElementsCatalogue(c *fiber.Ctx) responseMessage {
+	data := make([]ElementTypeData, len(config.Elements))
+
+	for ii, elementType := range config.Elements {
+		data[ii] = ElementTypeData{
+			ID:          elementType.ID,
+			Name:        elementType.Name,
+			Gender:      elementType.Gender,
+			Description: elementType.Description,
+			Location:    elementType.Location,
+			Price:       elementType.Price,
+			Capacity:    elementType.Capacity,
+		}
+
+		// count the elements of the type in the layout
+		for _, typeID := range config.Layout.types {
+			if typeID == elementType.ID {
+				data[ii].Count++
+			}
+		}
+	}
+
+	return responseMessage{Data: data}
+}
@@ -1,86 +1,252 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"net/url"
 	"os"
-	"os/exec"
 	"path"
+	"strings"
 	"time"
 
+	"github.com/gofiber/fiber/v2"
 	mail "github.com/xhit/go-simple-mail/v2"
 )
 
+// characters of the certificate-serials, without the ones that are easily confused (0, O, 1, I)
+const certificateSerialAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// number of characters of a certificate-serial, grouped in blocks of four
+const certificateSerialLength = 12
+
+// issued certificate in the database
+type CertificateDB struct {
+	Id      int    `json:"id"`
+	Serial  string `json:"serial"`
+	Mid     string `json:"mid"`
+	Name    string `json:"name"`
+	Issued  int64  `json:"issued"`
+	Revoked *int64 `json:"revoked"`
+	// sha256-hash of the archived pdf, nil if it wasn't rendered yet
+	Hash *string `json:"hash"`
+}
+
+// public result of the verification of a certificate
+type CertificateVerification struct {
+	Serial  string `json:"serial"`
+	Element string `json:"element"`
+	Name    string `json:"name"`
+	Issued  string `json:"issued"`
+	Revoked string `json:"revoked,omitempty"`
+	Valid   bool   `json:"valid"`
+}
+
 type CertificateData struct {
 	Reservation  ReservationData
 	TemplateData SponsorshipTemplateData
 	PDFFile      string
+	// render the certificate again instead of serving it from the archive
+	Regenerate bool
+	// render a sample-certificate with a template instead of issuing one
+	Preview *CertificatePreview
+	// working-directory of the render-job inside the archive
+	workDir string
 }
 
 type SponsorshipTemplateData struct {
-	Element string
-	Article string
-	Date    string
-	Name    string
+	// id of the element-type, for choosing the template
+	Type     string
+	Element  string
+	Article  string
+	Location string
+	Price    string
+	Capacity string
+	Date     string
+	Name     string
+	// serial-number of the certificate and the link to verify it
+	Serial    string
+	VerifyURL string
+	// qr-code of the verification-link as data-url, e.g. for the svg-templates
+	QRCode string
 }
 
 var months = [12]string{
 	"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember",
 }
 
+// formats a date like "3. März 2025"
+func formatLongDate(date time.Time) string {
+	return date.Format(fmt.Sprintf("2. %s 2006", months[date.Month()-1]))
+}
+
+// name and number of an element, e.g. "PV-Modul 12"
+func elementDescription(mid string) string {
+	elementType, _ := lookupElement(mid)
+
+	return fmt.Sprintf("%s %s", elementType.Name, getElementID(mid))
+}
+
 func (data *SponsorshipTemplateData) populate(mid, name string) {
+	elementType, _ := lookupElement(mid)
+
 	*data = SponsorshipTemplateData{
-		Name:    name,
-		Element: fmt.Sprintf("%s %s", getElementType(mid), getElementID(mid)),
-		Article: getElementArticle(mid),
-		Date:    time.Now().Format(fmt.Sprintf("2. %s 2006", months[time.Now().Month()-1])),
+		Type:     elementType.ID,
+		Name:     name,
+		Element:  elementDescription(mid),
+		Article:  genderArticles[elementType.Gender].Definite,
+		Location: elementType.Location,
+		Price:    formatEuro(elementType.Price),
+		Capacity: elementType.Capacity,
+		Date:     formatLongDate(time.Now()),
+	}
+}
+
+// creates a random certificate-serial, e.g. "7KQ2-MX9P-4HTA"
+func newCertificateSerial() (string, error) {
+	var serial strings.Builder
+
+	for ii := range certificateSerialLength {
+		if ii > 0 && ii%4 == 0 {
+			serial.WriteByte('-')
+		}
+
+		if n, err := rand.Int(rand.Reader, big.NewInt(int64(len(certificateSerialAlphabet)))); err != nil {
+			return "", err
+		} else {
+			serial.WriteByte(certificateSerialAlphabet[n.Int64()])
+		}
 	}
+
+	return serial.String(), nil
+}
+
+// brings a user-entered serial into the stored format, ignoring case, spaces and dashes
+func normalizeCertificateSerial(serial string) string {
+	serial = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(serial))
+
+	var groups []string
+
+	for ii := 0; ii < len(serial); ii += 4 {
+		groups = append(groups, serial[ii:min(ii+4, len(serial))])
+	}
+
+	return strings.Join(groups, "-")
+}
+
+// creates the public link to verify a certificate
+func certificateVerifyURL(serial string) string {
+	return fmt.Sprintf("%s/api/certificates/verify/%s", strings.TrimSuffix(config.Server.URL, "/"), url.PathEscape(serial))
 }
 
 func (data *CertificateData) create() error {
+	if data.Preview != nil {
+		return data.preview()
+	}
+
 	// populate the template-data
 	data.TemplateData.populate(data.Reservation.Mid, data.Reservation.Name)
 
-	// choose the svg-template wether a name is given or not
-	var templateName string
+	serial, err := newCertificateSerial()
 
-	if data.Reservation.Name == "" {
-		templateName = "template_without_name.svg"
-	} else {
-		templateName = "template_with_name.svg"
+	if err != nil {
+		return err
 	}
 
-	// open the svg-template
-	// create temporary svg file
-	if svgFile, err := os.CreateTemp("templates", "certificate.*.svg"); err != nil {
+	// reuse the serial of the valid certificate of the element or issue a new one
+	certificate, err := store.IssueCertificate(data.Reservation.Mid, data.Reservation.Name, serial, time.Now().Unix())
+
+	if err != nil {
 		return err
-	} else {
-		defer os.Remove(svgFile.Name())
-		defer svgFile.Close()
+	}
 
-		if svgString, err := parseTemplate(path.Join("templates", templateName), data.TemplateData); err != nil {
-			return err
+	// the certificate always shows its original issue-date
+	data.TemplateData.Serial = certificate.Serial
+	data.TemplateData.VerifyURL = certificateVerifyURL(certificate.Serial)
+	data.TemplateData.Date = formatLongDate(time.Unix(certificate.Issued, 0))
+
+	if data.TemplateData.QRCode, err = qrDataURL(data.TemplateData.VerifyURL); err != nil {
+		return err
+	}
+
+	// serve the archived pdf, unless a new one is requested
+	if certificate.Hash != nil && !data.Regenerate {
+		archived := certificateArchivePath(*certificate.Hash)
+
+		if _, err := os.Stat(archived); err == nil {
+			data.PDFFile = archived
+
+			return nil
 		} else {
-			data.PDFFile = fmt.Sprintf("templates/certificate.%s.pdf", data.Reservation.Mid)
+			logger.Warn().Msgf("archived certificate %q of %q is missing, rendering it again: %v", certificate.Serial, data.Reservation.Mid, err)
+		}
+	}
 
-			// write the svg-template
-			svgFile.WriteString(svgString)
+	return data.archive(certificate)
+}
 
-			actionString := fmt.Sprintf(`--actions=export-filename:%s; export-area-page; export-do`, data.PDFFile)
+// path of an archived certificate by the hash of its content
+func certificateArchivePath(hash string) string {
+	return path.Join(config.Certificate.Archive, hash+".pdf")
+}
 
-			// create a pdf from the svg-file
-			command := exec.Command("inkscape/AppRun", actionString, svgFile.Name())
+// returns the hex-encoded sha256-hash of a file
+func hashFile(name string) (string, error) {
+	if file, err := os.Open(name); err != nil {
+		return "", err
+	} else {
+		defer file.Close()
 
-			if err := command.Run(); err != nil {
-				logger.Error().Msg(err.Error())
+		hash := sha256.New()
 
-				return err
-			}
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
 
-			return nil
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+}
+
+// renders the certificate into the archive and stores its hash
+func (data *CertificateData) archive(certificate CertificateDB) error {
+	// render into the working-directory of the job, which is inside the archive, so the file can be moved without copying
+	pdfFile := path.Join(data.workDir, data.fileName())
+
+	if err := exportCertificate(data.TemplateData, pdfFile); err != nil {
+		logger.Error().Msg(err.Error())
+
+		return err
+	} else if hash, err := hashFile(pdfFile); err != nil {
+		return err
+	} else if err := os.Rename(pdfFile, certificateArchivePath(hash)); err != nil {
+		return err
+	} else if err := store.SetCertificateHash(certificate.Id, hash); err != nil {
+		return err
+	} else {
+		// remove the replaced pdf of a regenerated certificate
+		if certificate.Hash != nil && *certificate.Hash != hash {
+			if err := os.Remove(certificateArchivePath(*certificate.Hash)); err != nil && !os.IsNotExist(err) {
+				logger.Warn().Msgf("can't remove replaced certificate %q of %q: %v", certificate.Serial, data.Reservation.Mid, err)
+			}
 		}
+
+		data.PDFFile = certificateArchivePath(hash)
+
+		logger.Info().Msgf("archived certificate %q of %q", certificate.Serial, data.Reservation.Mid)
+
+		return nil
 	}
 }
 
+// name of the pdf-file for the sponsor
+func (data CertificateData) fileName() string {
+	return fmt.Sprintf("certificate.%s.pdf", data.Reservation.Mid)
+}
+
 func (data CertificateData) send() error {
 	email := mail.NewMSG()
 
@@ -99,24 +265,114 @@ func (data CertificateData) send() error {
 
 		email.Attach(&mail.File{
 			FilePath: data.PDFFile,
+			Name:     data.fileName(),
 		})
 
-		if mailClient, err := mailServer.Connect(); err != nil {
-			logger.Fatal().Msgf("can't connect to to mail-server: %v", err)
+		return enqueueMail(email, subject)
+	}
+}
+
+// handles the public verification of a certificate by its serial
+func getCertificatesVerify(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	serial := normalizeCertificateSerial(c.Params("serial"))
+
+	if certificate, err := store.GetCertificate(serial); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get certificate %q from database: %v", serial, err)
+	} else if certificate == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "certificate doesn't exist"
+
+		logger.Info().Msgf("can't verify certificate %q: certificate doesn't exist", serial)
+	} else {
+		verification := CertificateVerification{
+			Serial:  certificate.Serial,
+			Element: elementDescription(certificate.Mid),
+			Name:    certificate.Name,
+			Issued:  formatLongDate(time.Unix(certificate.Issued, 0)),
+			Valid:   certificate.Revoked == nil,
+		}
+
+		if certificate.Revoked != nil {
+			verification.Revoked = formatLongDate(time.Unix(*certificate.Revoked, 0))
+		}
+
+		response.Data = verification
+	}
+
+	return response
+}
 
-			return err
-		} else if err := email.Send(mailClient); err != nil {
-			return err
+// renders the valid certificate of an element again with its original serial and issue-date, e.g. after a template-change
+func patchCertificates(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if mid := c.Query("mid"); mid == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include mid"
+
+		logger.Info().Msg("query doesn't include mid")
+	} else if element, err := store.GetElement(mid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get element %q from database: %v", mid, err)
+	} else if element == nil || element.Reservation != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid mid"
+
+		logger.Info().Msgf("query doesn't include valid mid: %q", mid)
+	} else {
+		certData := CertificateData{
+			Reservation: ReservationData{
+				Mid:  mid,
+				Name: element.Name,
+			},
+			Regenerate: true,
+		}
+
+		if _, err := renderCertificate(certData); errors.Is(err, errRenderQueueFull) {
+			response.Status = fiber.StatusServiceUnavailable
+			response.Message = "too many certificates are being rendered"
+
+			logger.Warn().Msgf("can't regenerate certificate for %q: %v", mid, err)
+		} else if err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't regenerate certificate for %q: %v", mid, err)
 		} else {
-			return nil
+			response = getSponsorships(c)
 		}
 	}
+
+	return response
 }
 
-func (data *CertificateData) cleanup() error {
-	if data.PDFFile != "" {
-		return os.Remove(data.PDFFile)
+// revokes the valid certificate of an element
+func deleteCertificates(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if mid := c.Query("mid"); mid == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include mid"
+
+		logger.Info().Msg("query doesn't include mid")
+	} else if ok, err := store.RevokeCertificates(mid, time.Now().Unix()); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't revoke certificate of %q: %v", mid, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "element has no valid certificate"
+
+		logger.Info().Msgf("can't revoke certificate of %q: element has no valid certificate", mid)
 	} else {
-		return nil
+		logger.Info().Msgf("revoked certificate of %q", mid)
+
+		response = getSponsorships(c)
 	}
+
+	return response
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/go-pdf/fpdf/contrib/gofpdi"
+	"github.com/gofiber/fiber/v2"
+)
+
+// formats of the certificate-export
+const (
+	exportZIP = "zip"
+	exportPDF = "pdf"
+)
+
+// parses an optional date of the export-filter
+func parseExportDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	} else {
+		return time.ParseInLocation(time.DateOnly, date, time.Local)
+	}
+}
+
+// opens the pdf-files of the certificates, so the export can't fail halfway because one of them is missing
+func openCertificateFiles(certificates []CertificateData) ([]*os.File, error) {
+	files := make([]*os.File, 0, len(certificates))
+
+	for _, certificate := range certificates {
+		if file, err := os.Open(certificate.PDFFile); err != nil {
+			closeFiles(files)
+
+			return nil, err
+		} else {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// writes the opened certificates as a zip-archive with one file per element
+func writeCertificatesZIP(w io.Writer, certificates []CertificateData, files []*os.File) error {
+	archive := zip.NewWriter(w)
+
+	for ii, certificate := range certificates {
+		if file, err := archive.Create(certificate.fileName()); err != nil {
+			return err
+		} else if _, err := io.Copy(file, files[ii]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// merges the opened certificates into a single pdf for printing, keeping the page-size of every certificate
+func mergeCertificatesPDF(certificates []CertificateData, files []*os.File) (pdf *fpdf.Fpdf, err error) {
+	// gofpdi panics on pdf-files it can't parse
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("can't import certificate: %v", r)
+		}
+	}()
+
+	pdf = fpdf.New("P", "pt", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+
+	importer := gofpdi.NewImporter()
+
+	for ii, certificate := range certificates {
+		var source io.ReadSeeker = files[ii]
+
+		template := importer.ImportPageFromStream(pdf, &source, 1, "/MediaBox")
+
+		size := importer.GetPageSizes()[1]["/MediaBox"]
+		width, height := size["w"], size["h"]
+
+		orientation := "P"
+		if width > height {
+			orientation = "L"
+		}
+
+		pdf.AddPageFormat(orientation, fpdf.SizeType{Wd: width, Ht: height})
+		importer.UseImportedTemplate(pdf, template, 0, 0, width, height)
+
+		if pdf.Err() {
+			return nil, fmt.Errorf("can't import certificate for %q: %w", certificate.Reservation.Mid, pdf.Error())
+		}
+	}
+
+	return pdf, nil
+}
+
+// handles get-requests to export the certificates of all sponsorships or of those matching the filter,
+// the date-range applies to the issue-date, certificates that aren't issued yet count as issued today
+func getCertificatesExport(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	elementType := c.Query("type")
+	format := cmp.Or(c.Query("format"), exportZIP)
+
+	if _, ok := config.ElementTypes[elementType]; elementType != "" && !ok {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "unknown element-type"
+
+		logger.Info().Msgf("can't export certificates: unknown element-type %q", elementType)
+	} else if format != exportZIP && format != exportPDF {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid format"
+
+		logger.Info().Msgf("can't export certificates: invalid format %q", format)
+	} else if from, err := parseExportDate(c.Query("from")); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid start-date"
+
+		logger.Info().Msgf("can't export certificates: invalid start-date %q", c.Query("from"))
+	} else if to, err := parseExportDate(c.Query("to")); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid end-date"
+
+		logger.Info().Msgf("can't export certificates: invalid end-date %q", c.Query("to"))
+	} else if sponsorships, err := store.GetSponsorships(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get sponsored elements from database: %v", err)
+	} else if validCertificates, err := store.GetValidCertificates(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get certificates from database: %v", err)
+	} else {
+		// issue-dates of the valid certificates by element
+		issued := map[string]CertificateDB{}
+
+		for _, certificate := range validCertificates {
+			issued[certificate.Mid] = certificate
+		}
+
+		var certificates []CertificateData
+
+		for _, sponsorship := range sponsorships {
+			issueDate := time.Now()
+
+			if certificate, ok := issued[sponsorship.Mid]; ok && certificate.Name == sponsorship.Name {
+				issueDate = time.Unix(certificate.Issued, 0)
+			}
+
+			if elementTypeData, _ := lookupElement(sponsorship.Mid); elementType != "" && elementTypeData.ID != elementType {
+				continue
+			} else if !from.IsZero() && issueDate.Before(from) {
+				continue
+			} else if !to.IsZero() && !issueDate.Before(to.AddDate(0, 0, 1)) {
+				continue
+			}
+
+			certificates = append(certificates, CertificateData{
+				Reservation: ReservationData{
+					Mid:  sponsorship.Mid,
+					Name: sponsorship.Name,
+				},
+			})
+		}
+
+		// predictable order for the zip-archive and the printout
+		slices.SortFunc(certificates, func(a, b CertificateData) int {
+			return cmp.Compare(a.Reservation.Mid, b.Reservation.Mid)
+		})
+
+		if len(certificates) == 0 {
+			response.Status = fiber.StatusNotFound
+			response.Message = "no certificates match the filter"
+
+			logger.Info().Msg("can't export certificates: no certificates match the filter")
+		} else if certificates, err = renderCertificates(certificates); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't create certificates for the export: %v", err)
+		} else if files, err := openCertificateFiles(certificates); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't open certificates for the export: %v", err)
+		} else if format == exportZIP {
+			logger.Info().Msgf("exporting %d certificates as %s", len(certificates), format)
+
+			c.Attachment("certificates." + format)
+
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				defer closeFiles(files)
+
+				if err := writeCertificatesZIP(w, certificates, files); err != nil {
+					logger.Error().Msgf("can't write certificate-export: %v", err)
+				}
+			})
+		} else {
+			// the pdf is merged before the response starts, so a broken certificate still results in an error-status
+			pdf, err := mergeCertificatesPDF(certificates, files)
+
+			closeFiles(files)
+
+			if err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't merge certificates for the export: %v", err)
+			} else {
+				logger.Info().Msgf("exporting %d certificates as %s", len(certificates), format)
+
+				c.Attachment("certificates." + format)
+
+				c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+					if err := pdf.Output(w); err != nil {
+						logger.Error().Msgf("can't write certificate-export: %v", err)
+					}
+				})
+			}
+		}
+	}
+
+	return response
+}
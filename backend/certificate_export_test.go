@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// writes a single-page pdf and returns the certificate using it
+func testCertificatePDF(t *testing.T, mid string, orientation string) CertificateData {
+	pdf := fpdf.New(orientation, "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.Text(20, 20, mid)
+
+	path := filepath.Join(t.TempDir(), mid+".pdf")
+
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		t.Fatalf("can't write pdf: %v", err)
+	}
+
+	return CertificateData{Reservation: ReservationData{Mid: mid}, PDFFile: path}
+}
+
+func TestMergeCertificatesPDF(t *testing.T) {
+	certificates := []CertificateData{testCertificatePDF(t, "pv-a1", "P"), testCertificatePDF(t, "pv-a2", "L")}
+
+	files, err := openCertificateFiles(certificates)
+	if err != nil {
+		t.Fatalf("can't open certificates: %v", err)
+	}
+
+	pdf, err := mergeCertificatesPDF(certificates, files)
+
+	closeFiles(files)
+
+	if err != nil {
+		t.Fatalf("can't merge certificates: %v", err)
+	} else if pages := pdf.PageCount(); pages != 2 {
+		t.Errorf("merged pdf has %d pages, expected 2", pages)
+	}
+
+	// the merged pdf is complete without the source-files
+	var output bytes.Buffer
+
+	if err := pdf.Output(&output); err != nil {
+		t.Errorf("can't write merged pdf: %v", err)
+	} else if !bytes.HasPrefix(output.Bytes(), []byte("%PDF-")) {
+		t.Error("merged certificates aren't a pdf")
+	}
+
+	// a broken certificate results in an error instead of a panic
+	broken := CertificateData{Reservation: ReservationData{Mid: "pv-a3"}, PDFFile: filepath.Join(t.TempDir(), "pv-a3.pdf")}
+
+	if err := os.WriteFile(broken.PDFFile, []byte("no pdf"), 0o600); err != nil {
+		t.Fatalf("can't write broken certificate: %v", err)
+	}
+
+	certificates = append(certificates, broken)
+
+	files, err = openCertificateFiles(certificates)
+	if err != nil {
+		t.Fatalf("can't open certificates: %v", err)
+	}
+
+	_, err = mergeCertificatesPDF(certificates, files)
+
+	closeFiles(files)
+
+	if err == nil {
+		t.Error("broken certificate was merged")
+	}
+
+	// missing certificates are noticed before the export starts
+	if _, err := openCertificateFiles(append(certificates, CertificateData{PDFFile: filepath.Join(t.TempDir(), "missing.pdf")})); err == nil {
+		t.Error("missing certificate was opened")
+	}
+}
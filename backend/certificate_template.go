@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"text/template"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// variants of the svg-templates, wether the sponsor is named on the certificate or not
+const (
+	variantWithName    = "with_name"
+	variantWithoutName = "without_name"
+)
+
+// formats of the template-preview
+const (
+	previewPDF = "pdf"
+	previewPNG = "png"
+)
+
+// name of the sponsor in the sample-data of the template-validation and -preview
+const sampleSponsorName = "Erika Mustermann"
+
+var errPreviewUnsupported = errors.New("png-previews require the inkscape-renderer")
+
+// new version of a certificate-template for the database, the version is assigned by the store
+type CertificateTemplateEntryDB struct {
+	Type    string
+	Variant string
+	Version int
+	Content string
+	Created int64
+}
+
+// certificate-template without its content
+type CertificateTemplateDB struct {
+	Id      int    `json:"id"`
+	Type    string `json:"type"`
+	Variant string `json:"variant"`
+	Version int    `json:"version"`
+	Active  bool   `json:"active"`
+	Created int64  `json:"created"`
+}
+
+// certificate-template with its content
+type CertificateTemplateFileDB struct {
+	Id      int
+	Type    string
+	Variant string
+	Version int
+	Content string
+}
+
+// sample-certificate rendered with a template that isn't necessarily active
+type CertificatePreview struct {
+	Template string
+	Format   string
+	Result   []byte
+}
+
+// returns the template-variant for a certificate with or without the name of the sponsor
+func templateVariant(named bool) string {
+	if named {
+		return variantWithName
+	} else {
+		return variantWithoutName
+	}
+}
+
+// returns the active svg-template of an element-type, falls back to the one for all element-types and then to the file in the templates-directory
+func certificateTemplate(elementType string, named bool) (*template.Template, error) {
+	variant := templateVariant(named)
+
+	for _, templateType := range slices.Compact([]string{elementType, ""}) {
+		if tpl, err := store.GetActiveCertificateTemplate(templateType, variant); err != nil {
+			return nil, err
+		} else if tpl != nil {
+			return template.New(fmt.Sprintf("%s %s v%d", tpl.Type, tpl.Variant, tpl.Version)).Parse(tpl.Content)
+		}
+	}
+
+	return loadTemplate(path.Join("templates", fmt.Sprintf("template_%s.svg", variant)))
+}
+
+// returns the template-data of a sample-certificate for the first element of a type, of any type if it is empty
+func sampleTemplateData(elementType string, named bool) SponsorshipTemplateData {
+	var mids []string
+
+	for mid, midType := range config.Layout.types {
+		if elementType == "" || midType == elementType {
+			mids = append(mids, mid)
+		}
+	}
+
+	slices.Sort(mids)
+
+	var data SponsorshipTemplateData
+
+	name := ""
+	if named {
+		name = sampleSponsorName
+	}
+
+	// without elements in the layout, use a made-up one
+	if len(mids) == 0 {
+		mids = append(mids, cmp.Or(elementType, "element")+"-1")
+	}
+
+	data.populate(mids[0], name)
+
+	data.Serial = "ABCD-EFGH-JKLM"
+	data.VerifyURL = certificateVerifyURL(data.Serial)
+	data.QRCode, _ = qrDataURL(data.VerifyURL)
+
+	return data
+}
+
+// test-executes a template with sample-data and checks that the result is a well-formed svg
+func validateCertificateTemplate(content, elementType, variant string) error {
+	if tpl, err := template.New("upload").Parse(content); err != nil {
+		return err
+	} else {
+		var buf bytes.Buffer
+
+		if err := tpl.Execute(&buf, sampleTemplateData(elementType, variant == variantWithName)); err != nil {
+			return err
+		}
+
+		if _, err := parseSVG(&buf); err != nil {
+			return fmt.Errorf("template doesn't create valid svg: %v", err)
+		}
+
+		return nil
+	}
+}
+
+// renders the sample-certificate of a preview into the working-directory of the job
+func (data *CertificateData) preview() error {
+	if tpl, err := template.New("preview").Parse(data.Preview.Template); err != nil {
+		return err
+	} else {
+		outputFile := path.Join(data.workDir, "preview."+data.Preview.Format)
+
+		if err := renderer.export(tpl, data.TemplateData, outputFile); err != nil {
+			return err
+		}
+
+		data.Preview.Result, err = os.ReadFile(outputFile)
+
+		return err
+	}
+}
+
+// handles get-requests for the versions of the certificate-templates
+func getCertificateTemplates(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if templates, err := store.GetCertificateTemplates(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get certificate-templates from database: %v", err)
+	} else {
+		response.Data = templates
+	}
+
+	return response
+}
+
+// handles get-requests to download the svg of a certificate-template
+func getCertificateTemplatesContent(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if tpl, err := store.GetCertificateTemplate(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get certificate-template %d from database: %v", id, err)
+	} else if tpl == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "template doesn't exist"
+
+		logger.Info().Msgf("certificate-template %d doesn't exist", id)
+	} else {
+		c.Attachment(fmt.Sprintf("template_%s.%s.v%d.svg", tpl.Variant, cmp.Or(tpl.Type, "all"), tpl.Version))
+		c.Type("svg")
+		c.SendString(tpl.Content)
+	}
+
+	return response
+}
+
+// handles post-requests to upload a new version of a certificate-template, it is validated but not activated
+func postCertificateTemplates(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Type    string `json:"type"`
+		Variant string `json:"variant"`
+		Content string `json:"content"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ type string; variant string; content string }"`)
+	} else if _, ok := config.ElementTypes[body.Type]; body.Type != "" && !ok {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "unknown element-type"
+
+		logger.Info().Msgf("can't add certificate-template: unknown element-type %q", body.Type)
+	} else if body.Variant != variantWithName && body.Variant != variantWithoutName {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid variant"
+
+		logger.Info().Msgf("can't add certificate-template: invalid variant %q", body.Variant)
+	} else if err := validateCertificateTemplate(body.Content, body.Type, body.Variant); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = fmt.Sprintf("invalid template: %v", err)
+
+		logger.Info().Msgf("can't add certificate-template: %v", err)
+	} else if version, err := store.AddCertificateTemplate(CertificateTemplateEntryDB{
+		Type:    body.Type,
+		Variant: body.Variant,
+		Content: body.Content,
+		Created: time.Now().Unix(),
+	}); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't add certificate-template: %v", err)
+	} else {
+		logger.Info().Msgf("added version %d of certificate-template %q %q", version, body.Type, body.Variant)
+
+		response = getCertificateTemplates(c)
+	}
+
+	return response
+}
+
+// handles patch-requests to activate a version of a certificate-template
+func patchCertificateTemplates(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if ok, err := store.ActivateCertificateTemplate(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't activate certificate-template %d: %v", id, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "template doesn't exist"
+
+		logger.Info().Msgf("can't activate certificate-template %d: template doesn't exist", id)
+	} else {
+		logger.Info().Msgf("activated certificate-template %d", id)
+
+		response = getCertificateTemplates(c)
+	}
+
+	return response
+}
+
+// handles patch-requests to go back to the previous version of a certificate-template,
+// rolling back the first version deactivates it, so the general template is used again
+func patchCertificateTemplatesRollback(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	templateType := c.Query("type")
+	variant := c.Query("variant")
+
+	if variant != variantWithName && variant != variantWithoutName {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid variant"
+
+		logger.Info().Msgf("can't roll back certificate-template: invalid variant %q", variant)
+	} else if ok, err := store.RollbackCertificateTemplate(templateType, variant); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't roll back certificate-template %q %q: %v", templateType, variant, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "no active template"
+
+		logger.Info().Msgf("can't roll back certificate-template %q %q: no active template", templateType, variant)
+	} else {
+		logger.Info().Msgf("rolled back certificate-template %q %q", templateType, variant)
+
+		response = getCertificateTemplates(c)
+	}
+
+	return response
+}
+
+// handles get-requests to render a version of a certificate-template with sample-data
+func getCertificateTemplatesPreview(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	format := cmp.Or(c.Query("format"), previewPDF)
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if format != previewPDF && format != previewPNG {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid format"
+
+		logger.Info().Msgf("can't preview certificate-template: invalid format %q", format)
+	} else if _, ok := renderer.(*inkscapeRenderer); format == previewPNG && !ok {
+		response.Status = fiber.StatusConflict
+		response.Message = errPreviewUnsupported.Error()
+
+		logger.Info().Msgf("can't preview certificate-template %d: %v", id, errPreviewUnsupported)
+	} else if tpl, err := store.GetCertificateTemplate(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get certificate-template %d from database: %v", id, err)
+	} else if tpl == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "template doesn't exist"
+
+		logger.Info().Msgf("certificate-template %d doesn't exist", id)
+	} else {
+		// render the preview with the worker-pool like every other certificate
+		certData := CertificateData{
+			TemplateData: sampleTemplateData(tpl.Type, tpl.Variant == variantWithName),
+			Preview: &CertificatePreview{
+				Template: tpl.Content,
+				Format:   format,
+			},
+		}
+
+		if certData, err = renderCertificate(certData); errors.Is(err, errRenderQueueFull) {
+			response.Status = fiber.StatusServiceUnavailable
+			response.Message = "too many certificates are being rendered"
+
+			logger.Warn().Msgf("can't preview certificate-template %d: %v", id, err)
+		} else if err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't preview certificate-template %d: %v", id, err)
+		} else {
+			c.Type(format)
+			c.Send(certData.Preview.Result)
+		}
+	}
+
+	return response
+}
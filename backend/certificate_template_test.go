@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+)
+
+// returns the version of the active template or 0 if there is none
+func activeTemplateVersion(t *testing.T, elementType, variant string) int {
+	if tpl, err := store.GetActiveCertificateTemplate(elementType, variant); err != nil {
+		t.Fatalf("can't get active template: %v", err)
+
+		return 0
+	} else if tpl == nil {
+		return 0
+	} else {
+		return tpl.Version
+	}
+}
+
+func TestRollbackCertificateTemplate(t *testing.T) {
+	setupTestStore(t)
+
+	for _, templateType := range []string{"", "", "", "pv-kirchendach"} {
+		if _, err := store.AddCertificateTemplate(CertificateTemplateEntryDB{Type: templateType, Variant: variantWithName, Content: "<svg/>"}); err != nil {
+			t.Fatalf("can't add template: %v", err)
+		}
+	}
+
+	templates, err := store.GetCertificateTemplates()
+	if err != nil {
+		t.Fatalf("can't get templates: %v", err)
+	}
+
+	// activate the newest general version and the template of the element-type
+	for _, tpl := range templates {
+		if tpl.Version == 3 || tpl.Type == "pv-kirchendach" {
+			if ok, err := store.ActivateCertificateTemplate(tpl.Id); err != nil || !ok {
+				t.Fatalf("can't activate template %d: %v", tpl.Id, err)
+			}
+		}
+	}
+
+	for _, expected := range []int{2, 1, 0} {
+		if ok, err := store.RollbackCertificateTemplate("", variantWithName); err != nil || !ok {
+			t.Fatalf("can't roll back template: %v", err)
+		} else if version := activeTemplateVersion(t, "", variantWithName); version != expected {
+			t.Errorf("version %d is active after the rollback, expected %d", version, expected)
+		}
+	}
+
+	// without an active version there is nothing to roll back
+	if ok, err := store.RollbackCertificateTemplate("", variantWithName); err != nil || ok {
+		t.Errorf("rollback without active template returned %v, %v", ok, err)
+	}
+
+	// the templates of other element-types aren't touched
+	if version := activeTemplateVersion(t, "pv-kirchendach", variantWithName); version != 1 {
+		t.Errorf("version %d of the element-type is active, expected 1", version)
+	}
+}
+
+func TestValidateCertificateTemplate(t *testing.T) {
+	tests := []struct {
+		content string
+		valid   bool
+	}{
+		{`<svg xmlns="http://www.w3.org/2000/svg"><text>{{.Name}} {{.Element}}</text><image href="{{.QRCode}}"/></svg>`, true},
+		{`<svg><text>{{.Name}</text></svg>`, false},
+		{`<svg><text>{{.Unknown}}</text></svg>`, false},
+		{`<svg><text>{{.Name}}</svg>`, false},
+	}
+
+	for _, test := range tests {
+		if err := validateCertificateTemplate(test.content, "", variantWithName); test.valid && err != nil {
+			t.Errorf("template %q is invalid: %v", test.content, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("invalid template %q was accepted", test.content)
+		}
+	}
+}
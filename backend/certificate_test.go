@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestIssueCertificate(t *testing.T) {
+	setupTestStore(t)
+
+	first, err := store.IssueCertificate("pv-a1", "Erika Mustermann", "AAAA-AAAA-AAAA", 100)
+	if err != nil {
+		t.Fatalf("can't issue certificate: %v", err)
+	}
+
+	// the valid certificate is kept as long as the name doesn't change
+	if again, err := store.IssueCertificate("pv-a1", "Erika Mustermann", "BBBB-BBBB-BBBB", 200); err != nil {
+		t.Fatalf("can't issue certificate: %v", err)
+	} else if again.Serial != first.Serial || again.Issued != 100 {
+		t.Errorf("certificate %q issued at %d, expected the existing one %q", again.Serial, again.Issued, first.Serial)
+	}
+
+	// a new name revokes the old certificate
+	if renamed, err := store.IssueCertificate("pv-a1", "Max Mustermann", "CCCC-CCCC-CCCC", 300); err != nil {
+		t.Fatalf("can't issue certificate: %v", err)
+	} else if renamed.Serial != "CCCC-CCCC-CCCC" || renamed.Issued != 300 {
+		t.Errorf("certificate %q issued at %d, expected a new one", renamed.Serial, renamed.Issued)
+	}
+
+	if old, err := store.GetCertificate(first.Serial); err != nil {
+		t.Fatalf("can't get certificate: %v", err)
+	} else if old == nil || old.Revoked == nil || *old.Revoked != 300 {
+		t.Errorf("old certificate %+v isn't revoked", old)
+	}
+
+	if valid, err := store.GetValidCertificates(); err != nil {
+		t.Fatalf("can't get valid certificates: %v", err)
+	} else if len(valid) != 1 || valid[0].Name != "Max Mustermann" {
+		t.Errorf("valid certificates %+v, expected only the renamed one", valid)
+	}
+}
+
+func TestIssueCertificateConcurrent(t *testing.T) {
+	setupTestStore(t)
+
+	const requests = 20
+
+	var wg sync.WaitGroup
+	serials := make(chan string, requests)
+
+	for ii := 0; ii < requests; ii++ {
+		wg.Add(1)
+
+		go func(ii int) {
+			defer wg.Done()
+
+			if certificate, err := store.IssueCertificate("pv-a1", "Erika Mustermann", fmt.Sprintf("AAAA-AAAA-%04d", ii), 100); err != nil {
+				t.Errorf("can't issue certificate: %v", err)
+			} else {
+				serials <- certificate.Serial
+			}
+		}(ii)
+	}
+
+	wg.Wait()
+	close(serials)
+
+	issued := map[string]bool{}
+
+	for serial := range serials {
+		issued[serial] = true
+	}
+
+	if len(issued) != 1 {
+		t.Errorf("the requests got %d different serials, expected exactly 1", len(issued))
+	}
+
+	// the database rejects a second valid certificate
+	if _, err := store.(*sqlStore).db.Exec("INSERT INTO certificates (serial, mid, name, issued) VALUES ('ZZZZ-ZZZZ-ZZZZ', 'pv-a1', 'Erika Mustermann', 100)"); err == nil {
+		t.Error("second valid certificate for the same element was stored")
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -16,9 +15,83 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// key of the jwt-keyring in the config-file
+type JWTKeyYaml struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+	// time of the retirement in RFC 3339, empty for active keys
+	Retired string `yaml:"retired,omitempty"`
+}
+
+// type of elements in the catalogue of the config-file
+type ElementTypeYaml struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// grammatical gender of the name: "m", "f" or "n"
+	Gender      string  `yaml:"gender"`
+	Description string  `yaml:"description"`
+	Location    string  `yaml:"location,omitempty"`
+	Price       float64 `yaml:"price"`
+	Capacity    string  `yaml:"capacity,omitempty"`
+}
+
+// row of elements in a section of the layout
+type LayoutRowYaml struct {
+	// prefix of the element-ids, the slots are numbered from 1
+	Prefix  string `yaml:"prefix"`
+	Columns int    `yaml:"columns"`
+	// number of empty columns before the first slot
+	Offset int `yaml:"offset,omitempty"`
+	// numbers of the slots without an element
+	Unavailable []int `yaml:"unavailable,omitempty"`
+}
+
+// section of the layout with elements of a single type, e.g. a roof
+type LayoutSectionYaml struct {
+	ID   string `yaml:"id"`
+	Type string `yaml:"type"`
+	// css-class of the elements in the client
+	Class string  `yaml:"class,omitempty"`
+	X     float64 `yaml:"x"`
+	Y     float64 `yaml:"y"`
+	// "portrait" or "landscape", the element-size is given in portrait
+	Orientation   string          `yaml:"orientation"`
+	ElementWidth  float64         `yaml:"element_width"`
+	ElementHeight float64         `yaml:"element_height"`
+	Gap           float64         `yaml:"gap"`
+	Rows          []LayoutRowYaml `yaml:"rows"`
+}
+
+// layout of the elements in the config-file
+type LayoutYaml struct {
+	Width  float64 `yaml:"width"`
+	Height float64 `yaml:"height"`
+	// image below the elements, relative to the client
+	Background string              `yaml:"background,omitempty"`
+	Sections   []LayoutSectionYaml `yaml:"sections"`
+}
+
+// issuer of the donation-receipts in the config-file
+type ReceiptYaml struct {
+	// path of a text-template for the receipts, the built-in one is used if empty
+	Template string `yaml:"template,omitempty"`
+	Name     string `yaml:"name"`
+	Address  string `yaml:"address"`
+	// tax-office, tax-number and date of the exemption-notice
+	TaxOffice  string `yaml:"tax_office"`
+	TaxNumber  string `yaml:"tax_number"`
+	NoticeDate string `yaml:"notice_date"`
+	// tax-privileged purpose, e.g. "des Umweltschutzes"
+	Purpose   string `yaml:"purpose"`
+	Place     string `yaml:"place"`
+	Signatory string `yaml:"signatory"`
+}
+
 type ConfigYaml struct {
 	LogLevel string `yaml:"log_level"`
 	Database struct {
+		Driver   string `yaml:"driver"`
+		File     string `yaml:"file"`
 		Host     string `yaml:"host"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
@@ -29,32 +102,76 @@ type ConfigYaml struct {
 		Purge      string `yaml:"purge"`
 	} `yaml:"cache"`
 	ClientSession struct {
-		JwtSignature string `yaml:"jwt_signature"`
-		Expire       string `yaml:"expire"`
+		JwtSignature string       `yaml:"jwt_signature,omitempty"`
+		Expire       string       `yaml:"expire"`
+		KeyGrace     string       `yaml:"key_grace"`
+		Keys         []JWTKeyYaml `yaml:"keys"`
 	} `yaml:"client_session"`
 	Server struct {
-		Port int `yaml:"port"`
+		Port        int    `yaml:"port"`
+		URL         string `yaml:"url"`
+		ProxyHeader string `yaml:"proxy_header"`
 	} `yaml:"server"`
 	Reservation struct {
-		Expiration string `yaml:"expiration"`
+		Expiration             string `yaml:"expiration"`
+		ConfirmationExpiration string `yaml:"confirmation_expiration"`
 	} `yaml:"reservation"`
-	Mail struct {
-		Server    string `yaml:"server"`
-		Port      int    `yaml:"port"`
-		User      string `yaml:"user"`
-		Password  string `yaml:"password"`
-		Templates struct {
+	Payment struct {
+		Beneficiary string `yaml:"beneficiary"`
+		IBAN        string `yaml:"iban"`
+		BIC         string `yaml:"bic"`
+	} `yaml:"payment"`
+	Receipt ReceiptYaml `yaml:"receipt"`
+	Mail    struct {
+		Transport  string `yaml:"transport"`
+		Server     string `yaml:"server"`
+		Port       int    `yaml:"port"`
+		Encryption string `yaml:"encryption"`
+		User       string `yaml:"user"`
+		Password   string `yaml:"password"`
+		Sendmail   string `yaml:"sendmail"`
+		Directory  string `yaml:"directory"`
+		Templates  struct {
 			ReservationSubject string `yaml:"reservation_subject"`
 			CertificateSubject string `yaml:"certificate_subject"`
 		} `yaml:"subject_templates"`
+		Outbox struct {
+			Interval    string `yaml:"interval"`
+			Backoff     string `yaml:"backoff"`
+			MaxBackoff  string `yaml:"max_backoff"`
+			MaxAttempts int    `yaml:"max_attempts"`
+		} `yaml:"outbox"`
 	} `yaml:"mail"`
-	ValidateElements struct {
-		Regex         string `yaml:"regex"`
-		ValidElements map[string]struct {
-			From int `yaml:"from"`
-			To   int `yaml:"to"`
-		} `yaml:"valid_elements"`
-	} `yaml:"validate_elements"`
+	Certificate struct {
+		Renderer string `yaml:"renderer"`
+		Inkscape string `yaml:"inkscape"`
+		Font     string `yaml:"font"`
+		Fonts    []struct {
+			Family string `yaml:"family"`
+			Bold   bool   `yaml:"bold"`
+			Italic bool   `yaml:"italic"`
+			File   string `yaml:"file"`
+		} `yaml:"fonts"`
+		Archive string `yaml:"archive"`
+		Workers int    `yaml:"workers"`
+	} `yaml:"certificate"`
+	PasswordReset struct {
+		Expiration string `yaml:"expiration"`
+	} `yaml:"password_reset"`
+	Login struct {
+		Delay          string `yaml:"delay"`
+		MaxDelay       string `yaml:"max_delay"`
+		AccountLockout int    `yaml:"account_lockout"`
+		IPLockout      int    `yaml:"ip_lockout"`
+		Lockout        string `yaml:"lockout"`
+		Reset          string `yaml:"reset"`
+	} `yaml:"login"`
+	TOTP struct {
+		Issuer   string `yaml:"issuer"`
+		Required bool   `yaml:"required"`
+	} `yaml:"totp"`
+	Elements []ElementTypeYaml `yaml:"elements"`
+	Layout   LayoutYaml        `yaml:"layout"`
 }
 
 type CacheConfig struct {
@@ -63,16 +180,46 @@ type CacheConfig struct {
 }
 
 type ReservationConfig struct {
-	Expiration time.Duration
+	Expiration             time.Duration
+	ConfirmationExpiration time.Duration
+}
+
+type OutboxConfig struct {
+	Interval   time.Duration
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+type LoginConfig struct {
+	Delay          time.Duration
+	MaxDelay       time.Duration
+	AccountLockout int
+	IPLockout      int
+	Lockout        time.Duration
+	Reset          time.Duration
+}
+
+// key of the jwt-keyring
+type JWTKey struct {
+	ID      string
+	Secret  []byte
+	Retired time.Time
 }
 
 type ConfigStruct struct {
 	ConfigYaml
 	LogLevel      zerolog.Level
 	SessionExpire time.Duration
+	JWTKeys       []JWTKey
+	JWTKeyGrace   time.Duration
+	ResetExpire   time.Duration
 	Cache         CacheConfig
 	Reservation   ReservationConfig
-	MidRegex      *regexp.Regexp
+	Outbox        OutboxConfig
+	Login         LoginConfig
+	ElementTypes  map[string]ElementTypeYaml
+	Layout        Layout
+	BankAccount   BankAccount
 }
 
 var config ConfigStruct
@@ -92,12 +239,65 @@ func (w specificLevelWriter) WriteLevel(l zerolog.Level, p []byte) (int, error)
 	}
 }
 
+// id of the key from "client_session.jwt_signature", also used for tokens without kid-header
+const legacyJWTKeyID = "legacy"
+
+// returns the newest active key of the keyring
+func (config ConfigStruct) signingKey() (JWTKey, error) {
+	for ii := len(config.JWTKeys) - 1; ii >= 0; ii-- {
+		if config.JWTKeys[ii].Retired.IsZero() {
+			return config.JWTKeys[ii], nil
+		}
+	}
+
+	return JWTKey{}, fmt.Errorf("no active jwt-key")
+}
+
+// signs a token with the active key and adds its id as kid-header
+func (config ConfigStruct) signToken(t *jwt.Token) (string, error) {
+	if key, err := config.signingKey(); err != nil {
+		return "", err
+	} else {
+		t.Header["kid"] = key.ID
+
+		return t.SignedString(key.Secret)
+	}
+}
+
+// selects the key for verifying a token by its kid-header, retired keys are accepted until the grace-period ends
+func (config ConfigStruct) verificationKey(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected JWT signing method: %v", token.Header["alg"])
+	}
+
+	kid := legacyJWTKeyID
+
+	if header, ok := token.Header["kid"]; ok {
+		if kid, ok = header.(string); !ok {
+			return nil, fmt.Errorf("invalid kid-header: %v", header)
+		}
+	}
+
+	for _, key := range config.JWTKeys {
+		if key.ID == kid {
+			if !key.Retired.IsZero() && time.Now().After(key.Retired.Add(config.JWTKeyGrace)) {
+				return nil, fmt.Errorf("jwt-key %q is retired", kid)
+			}
+
+			return key.Secret, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown jwt-key %q", kid)
+}
+
 type Payload struct {
 	jwt.RegisteredClaims
 	CustomClaims map[string]any
 }
 
-func (config ConfigStruct) signJWT(val any) (string, error) {
+// signs a session-JWT with the id of the session
+func (config ConfigStruct) signJWT(id string, val any) (string, error) {
 	valMap, err := strucToMap(val)
 
 	if err != nil {
@@ -108,13 +308,97 @@ func (config ConfigStruct) signJWT(val any) (string, error) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.SessionExpire)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        id,
 		},
 		CustomClaims: valMap,
 	}
 
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	return t.SignedString([]byte(config.ClientSession.JwtSignature))
+	return config.signToken(t)
+}
+
+// payload of the reservation-confirmation-link
+type ConfirmationPayload struct {
+	jwt.RegisteredClaims
+	Mid          string `json:"mid"`
+	Confirmation string `json:"confirmation"`
+}
+
+// audience of the reservation-confirmation-link, so it can't be used as a session
+const confirmationAudience = "reservation-confirmation"
+
+func (config ConfigStruct) signConfirmationJWT(mid, confirmation string) (string, error) {
+	payload := ConfirmationPayload{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Reservation.ConfirmationExpiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Audience:  jwt.ClaimStrings{confirmationAudience},
+		},
+		Mid:          mid,
+		Confirmation: confirmation,
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+
+	return config.signToken(t)
+}
+
+// payload of the login-challenge between password and second factor
+type LoginChallengePayload struct {
+	jwt.RegisteredClaims
+	Uid int `json:"uid"`
+	Tid int `json:"tid"`
+}
+
+// audience of the login-challenge, so it can't be used as a session
+const loginChallengeAudience = "login-challenge"
+
+// time to enter the second factor after the password
+const loginChallengeExpiration = 5 * time.Minute
+
+func (config ConfigStruct) signLoginChallengeJWT(uid, tid int) (string, error) {
+	payload := LoginChallengePayload{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(loginChallengeExpiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Audience:  jwt.ClaimStrings{loginChallengeAudience},
+		},
+		Uid: uid,
+		Tid: tid,
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+
+	return config.signToken(t)
+}
+
+// payload of the password-reset-link
+type PasswordResetPayload struct {
+	jwt.RegisteredClaims
+	Uid int `json:"uid"`
+	Tid int `json:"tid"`
+}
+
+// audience of the password-reset-link, so it can't be used as a session
+const passwordResetAudience = "password-reset"
+
+// signs a password-reset-token, which is bound to the current token-id of the user,
+// so it becomes invalid with the changed password
+func (config ConfigStruct) signPasswordResetJWT(uid, tid int) (string, error) {
+	payload := PasswordResetPayload{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.ResetExpire)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Audience:  jwt.ClaimStrings{passwordResetAudience},
+		},
+		Uid: uid,
+		Tid: tid,
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+
+	return config.signToken(t)
 }
 
 func loadConfig() ConfigStruct {
@@ -135,6 +419,33 @@ func loadConfig() ConfigStruct {
 		os.Exit(1)
 	}
 
+	// default to mysql for older configs without a database-driver
+	if config.Database.Driver == "" {
+		config.Database.Driver = "mysql"
+	}
+
+	// default to the formerly hardcoded smtp with tls for older configs
+	if config.Mail.Transport == "" {
+		config.Mail.Transport = "smtp"
+	}
+
+	if config.Mail.Transport == "smtp" && config.Mail.Encryption == "" {
+		config.Mail.Encryption = "tls"
+	}
+
+	if config.TOTP.Issuer == "" {
+		config.TOTP.Issuer = "PV-Pate"
+	}
+
+	// inkscape is only used when it is selected
+	if config.Certificate.Renderer == "" {
+		config.Certificate.Renderer = "native"
+	}
+
+	if config.Certificate.Workers < 1 {
+		config.Certificate.Workers = 1
+	}
+
 	if logLevel, err := zerolog.ParseLevel(config.LogLevel); err != nil {
 		panic(fmt.Errorf("can't parse log-level: %v", err))
 	} else {
@@ -149,6 +460,32 @@ func loadConfig() ConfigStruct {
 			log.Fatalf(`Error parsing "cache.purge": %v`, err)
 		} else if reservationExpire, err := time.ParseDuration(config.Reservation.Expiration); err != nil {
 			log.Fatalf(`Error parsing "reservation.expiration": %v`, err)
+		} else if confirmationExpire, err := time.ParseDuration(config.Reservation.ConfirmationExpiration); err != nil {
+			log.Fatalf(`Error parsing "reservation.confirmation_expiration": %v`, err)
+		} else if outboxInterval, err := time.ParseDuration(config.Mail.Outbox.Interval); err != nil {
+			log.Fatalf(`Error parsing "mail.outbox.interval": %v`, err)
+		} else if outboxBackoff, err := time.ParseDuration(config.Mail.Outbox.Backoff); err != nil {
+			log.Fatalf(`Error parsing "mail.outbox.backoff": %v`, err)
+		} else if outboxMaxBackoff, err := time.ParseDuration(config.Mail.Outbox.MaxBackoff); err != nil {
+			log.Fatalf(`Error parsing "mail.outbox.max_backoff": %v`, err)
+		} else if keys, err := parseJWTKeys(config.ClientSession.JwtSignature, config.ClientSession.Keys); err != nil {
+			log.Fatalf(`Error parsing "client_session.keys": %v`, err)
+		} else if elementTypes, err := parseElementCatalogue(config.Elements); err != nil {
+			log.Fatalf(`Error parsing "elements": %v`, err)
+		} else if layout, err := parseLayout(config.Layout, elementTypes); err != nil {
+			log.Fatalf(`Error parsing "layout": %v`, err)
+		} else if bankAccount, err := parseBankAccount(config.Payment.Beneficiary, config.Payment.IBAN, config.Payment.BIC); err != nil {
+			log.Fatalf(`Error parsing "payment": %v`, err)
+		} else if resetExpire, err := time.ParseDuration(config.PasswordReset.Expiration); err != nil {
+			log.Fatalf(`Error parsing "password_reset.expiration": %v`, err)
+		} else if loginDelay, err := time.ParseDuration(config.Login.Delay); err != nil {
+			log.Fatalf(`Error parsing "login.delay": %v`, err)
+		} else if loginMaxDelay, err := time.ParseDuration(config.Login.MaxDelay); err != nil {
+			log.Fatalf(`Error parsing "login.max_delay": %v`, err)
+		} else if loginLockout, err := time.ParseDuration(config.Login.Lockout); err != nil {
+			log.Fatalf(`Error parsing "login.lockout": %v`, err)
+		} else if loginReset, err := time.ParseDuration(config.Login.Reset); err != nil {
+			log.Fatalf(`Error parsing "login.reset": %v`, err)
 
 			// parse the templates
 		} else {
@@ -156,14 +493,42 @@ func loadConfig() ConfigStruct {
 				ConfigYaml:    config,
 				LogLevel:      logLevel,
 				SessionExpire: session_expire,
+				JWTKeys:       keys,
+				JWTKeyGrace:   session_expire,
+				ResetExpire:   resetExpire,
 				Cache: CacheConfig{
 					Expiration: cacheExpire,
 					Purge:      cachePurge,
 				},
 				Reservation: ReservationConfig{
-					Expiration: reservationExpire,
+					Expiration:             reservationExpire,
+					ConfirmationExpiration: confirmationExpire,
+				},
+				Outbox: OutboxConfig{
+					Interval:   outboxInterval,
+					Backoff:    outboxBackoff,
+					MaxBackoff: outboxMaxBackoff,
 				},
-				MidRegex: regexp.MustCompile(config.ValidateElements.Regex),
+				Login: LoginConfig{
+					Delay:          loginDelay,
+					MaxDelay:       loginMaxDelay,
+					AccountLockout: config.Login.AccountLockout,
+					IPLockout:      config.Login.IPLockout,
+					Lockout:        loginLockout,
+					Reset:          loginReset,
+				},
+				ElementTypes: elementTypes,
+				Layout:       layout,
+				BankAccount:  bankAccount,
+			}
+		}
+
+		// retired keys are accepted as long as the session expiration, if not configured otherwise
+		if config.ClientSession.KeyGrace != "" {
+			if keyGrace, err := time.ParseDuration(config.ClientSession.KeyGrace); err != nil {
+				log.Fatalf(`Error parsing "client_session.key_grace": %v`, err)
+			} else {
+				configStruct.JWTKeyGrace = keyGrace
 			}
 		}
 
@@ -171,7 +536,42 @@ func loadConfig() ConfigStruct {
 	}
 }
 
-func init() {
+// creates the keyring from the legacy-signature and the configured keys
+func parseJWTKeys(legacySignature string, keysYaml []JWTKeyYaml) ([]JWTKey, error) {
+	keys := []JWTKey{}
+
+	if legacySignature != "" {
+		keys = append(keys, JWTKey{ID: legacyJWTKeyID, Secret: []byte(legacySignature)})
+	}
+
+	for _, keyYaml := range keysYaml {
+		key := JWTKey{ID: keyYaml.ID, Secret: []byte(keyYaml.Secret)}
+
+		if keyYaml.ID == "" || keyYaml.Secret == "" {
+			return nil, fmt.Errorf("key without id or secret")
+		}
+
+		if keyYaml.Retired != "" {
+			if retired, err := time.Parse(time.RFC3339, keyYaml.Retired); err != nil {
+				return nil, fmt.Errorf("can't parse retirement of key %q: %v", keyYaml.ID, err)
+			} else {
+				key.Retired = retired
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	// check that there is a key for signing
+	if _, err := (ConfigStruct{JWTKeys: keys}).signingKey(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// loads the config-file and sets up the logger
+func initConfig() {
 	config = loadConfig()
 
 	// try to set the log-level
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseJWTKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		legacy  string
+		keys    []JWTKeyYaml
+		ids     []string
+		signing string
+	}{
+		{name: "legacy signature only", legacy: "legacy-secret", ids: []string{legacyJWTKeyID}, signing: legacyJWTKeyID},
+		{
+			name:    "rotated keyring",
+			legacy:  "legacy-secret",
+			keys:    []JWTKeyYaml{{ID: "k1", Secret: "s1", Retired: "2024-01-01T00:00:00Z"}, {ID: "k2", Secret: "s2"}},
+			ids:     []string{legacyJWTKeyID, "k1", "k2"},
+			signing: "k2",
+		},
+		{name: "no keys"},
+		{name: "only retired keys", keys: []JWTKeyYaml{{ID: "k1", Secret: "s1", Retired: "2024-01-01T00:00:00Z"}}},
+		{name: "key without secret", legacy: "legacy-secret", keys: []JWTKeyYaml{{ID: "k1"}}},
+		{name: "key without id", legacy: "legacy-secret", keys: []JWTKeyYaml{{Secret: "s1"}}},
+		{name: "invalid retirement", keys: []JWTKeyYaml{{ID: "k1", Secret: "s1", Retired: "yesterday"}, {ID: "k2", Secret: "s2"}}},
+	}
+
+	for _, test := range tests {
+		keys, err := parseJWTKeys(test.legacy, test.keys)
+
+		if test.ids == nil {
+			if err == nil {
+				t.Errorf("%s: parsed keyring %+v, expected an error", test.name, keys)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("%s: can't parse keyring: %v", test.name, err)
+
+			continue
+		}
+
+		ids := make([]string, len(keys))
+		for ii, key := range keys {
+			ids[ii] = key.ID
+		}
+
+		if !slices.Equal(ids, test.ids) {
+			t.Errorf("%s: keyring has the keys %q, expected %q", test.name, ids, test.ids)
+		} else if key, err := (ConfigStruct{JWTKeys: keys}).signingKey(); err != nil || key.ID != test.signing {
+			t.Errorf("%s: signing-key is %q (%v), expected %q", test.name, key.ID, err, test.signing)
+		}
+	}
+}
+
+func TestVerificationKey(t *testing.T) {
+	now := time.Now()
+
+	testConfig := ConfigStruct{
+		JWTKeys: []JWTKey{
+			{ID: legacyJWTKeyID, Secret: []byte("legacy")},
+			{ID: "old", Secret: []byte("old"), Retired: now.Add(-2 * time.Hour)},
+			{ID: "recent", Secret: []byte("recent"), Retired: now.Add(-time.Minute)},
+			{ID: "current", Secret: []byte("current")},
+		},
+		JWTKeyGrace: time.Hour,
+	}
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    any
+		secret string
+	}{
+		{name: "active key", method: jwt.SigningMethodHS256, kid: "current", secret: "current"},
+		{name: "retired key within the grace-period", method: jwt.SigningMethodHS256, kid: "recent", secret: "recent"},
+		{name: "token without kid-header", method: jwt.SigningMethodHS256, secret: "legacy"},
+		{name: "retired key after the grace-period", method: jwt.SigningMethodHS256, kid: "old"},
+		{name: "unknown key", method: jwt.SigningMethodHS256, kid: "unknown"},
+		{name: "invalid kid-header", method: jwt.SigningMethodHS256, kid: 1},
+		{name: "other signing-method", method: jwt.SigningMethodRS256, kid: "current"},
+	}
+
+	for _, test := range tests {
+		token := jwt.New(test.method)
+
+		if test.kid != nil {
+			token.Header["kid"] = test.kid
+		}
+
+		key, err := testConfig.verificationKey(token)
+
+		if test.secret == "" {
+			if err == nil {
+				t.Errorf("%s: got a verification-key, expected an error", test.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: can't get verification-key: %v", test.name, err)
+		} else if secret, ok := key.([]byte); !ok || !bytes.Equal(secret, []byte(test.secret)) {
+			t.Errorf("%s: got verification-key %q, expected %q", test.name, key, test.secret)
+		}
+	}
+}
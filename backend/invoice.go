@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// name of the giro-code-image in the reservation-mail, referenced as "cid:girocode.png"
+const giroCodeName = "girocode.png"
+
+// name of the pdf-invoice for the sponsor
+func invoiceFileName(mid string) string {
+	return fmt.Sprintf("invoice.%s.pdf", mid)
+}
+
+// registers the font of the certificates for a document or falls back to helvetica, which needs the text in cp1252
+func documentFont(pdf *fpdf.Fpdf) (string, func(string) string) {
+	if config.Certificate.Font != "" {
+		pdf.AddUTF8Font(nativeFontFamily, "", config.Certificate.Font)
+
+		return nativeFontFamily, func(s string) string { return s }
+	} else {
+		return "Helvetica", pdf.UnicodeTranslatorFromDescriptor("")
+	}
+}
+
+// renders the invoice of a reservation with the payment-details and the giro-code
+func createInvoice(data ReservationTemplateData, giroCode []byte) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(25, 25, 25)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	family, translate := documentFont(pdf)
+
+	line := func(size float64, text string) {
+		pdf.SetFont(family, "", size)
+		pdf.MultiCell(width, pdf.PointToUnitConvert(size)*1.4, translate(text), "", "L", false)
+	}
+
+	// sender and date
+	pdf.SetFont(family, "", 10)
+	pdf.CellFormat(width, 5, translate(data.Beneficiary), "", 1, "R", false, 0, "")
+	pdf.CellFormat(width, 5, translate(data.Date), "", 1, "R", false, 0, "")
+
+	pdf.Ln(15)
+
+	line(20, "Rechnung")
+	line(10, fmt.Sprintf("Rechnungsnummer: %s", data.Reference))
+
+	pdf.Ln(10)
+
+	if data.Name != "" {
+		line(11, fmt.Sprintf("Für: %s", data.Name))
+		pdf.Ln(5)
+	}
+
+	// the reserved element
+	pdf.SetFont(family, "", 11)
+
+	description := data.Element
+	if data.Location != "" {
+		description = fmt.Sprintf("%s (%s)", data.Element, data.Location)
+	}
+
+	pdf.CellFormat(width*0.75, 8, translate("Patenschaft für "+description), "TB", 0, "L", false, 0, "")
+	pdf.CellFormat(width*0.25, 8, translate(data.Amount), "TB", 1, "R", false, 0, "")
+
+	pdf.SetFont(family, "", 11)
+	pdf.CellFormat(width*0.75, 8, translate("Gesamtbetrag"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(width*0.25, 8, translate(data.Amount), "", 1, "R", false, 0, "")
+
+	pdf.Ln(10)
+
+	// the payment-details are only available with a configured bank-account
+	if data.IBAN != "" {
+		line(11, fmt.Sprintf("Bitte überweisen Sie den Betrag unter Angabe des Verwendungszwecks %s auf folgendes Konto:", data.Reference))
+
+		pdf.Ln(3)
+
+		line(11, fmt.Sprintf("Empfänger: %s", data.Beneficiary))
+		line(11, fmt.Sprintf("IBAN: %s", data.IBAN))
+
+		if data.BIC != "" {
+			line(11, fmt.Sprintf("BIC: %s", data.BIC))
+		}
+
+		line(11, fmt.Sprintf("Verwendungszweck: %s", data.Reference))
+	} else {
+		line(11, fmt.Sprintf("Bitte geben Sie bei der Zahlung den Verwendungszweck %s an.", data.Reference))
+	}
+
+	if giroCode != nil {
+		pdf.Ln(8)
+
+		line(9, "Mit diesem GiroCode können Sie die Überweisung in Ihrer Banking-App ausführen:")
+
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+
+		pdf.RegisterImageOptionsReader(giroCodeName, options, bytes.NewReader(giroCode))
+		pdf.ImageOptions(giroCodeName, left, pdf.GetY()+2, 40, 40, false, options, 0, "")
+	}
+
+	var buf bytes.Buffer
+
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
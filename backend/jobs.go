@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// states of the render-jobs
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// number of render-jobs that can wait for a free worker
+const renderQueueLength = 100
+
+// time after which finished render-jobs are forgotten
+const renderJobExpiration = time.Hour
+
+// prefix of the working-directories of the render-jobs inside the archive
+const renderJobDirPrefix = "job."
+
+var errRenderQueueFull = errors.New("render-queue is full")
+
+// certificate rendered by the worker-pool
+type RenderJob struct {
+	Id       string `json:"id"`
+	Mid      string `json:"mid"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Created  int64  `json:"created"`
+	Finished int64  `json:"finished,omitempty"`
+
+	certificate CertificateData
+	// closed once the job is finished
+	done chan struct{}
+}
+
+// render-jobs by their id and the queue of the waiting ones
+var renderJobs = struct {
+	sync.Mutex
+	jobs  map[string]*RenderJob
+	queue chan *RenderJob
+}{
+	jobs: map[string]*RenderJob{},
+}
+
+// removes working-directories left over by an interrupted run and starts the render-workers
+func startRenderWorkers(workers int) {
+	if dirs, err := filepath.Glob(filepath.Join(config.Certificate.Archive, renderJobDirPrefix+"*")); err != nil {
+		logger.Warn().Msgf("can't list left over render-directories: %v", err)
+	} else {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}
+
+	renderJobs.queue = make(chan *RenderJob, renderQueueLength)
+
+	for range workers {
+		go runRenderWorker()
+	}
+}
+
+// renders the queued certificates one after another
+func runRenderWorker() {
+	for job := range renderJobs.queue {
+		setRenderJobStatus(job, jobRunning)
+
+		// render in a copy, so the job can be read while it is running
+		certificate := job.certificate
+
+		// every job gets its own working-directory inside the archive, so the results can be moved into it
+		if dir, err := os.MkdirTemp(config.Certificate.Archive, renderJobDirPrefix+"*"); err != nil {
+			finishRenderJob(job, certificate, err)
+		} else {
+			certificate.workDir = dir
+
+			err := certificate.create()
+
+			if err := os.RemoveAll(dir); err != nil {
+				logger.Warn().Msgf("can't remove working-directory of render-job %q: %v", job.Id, err)
+			}
+
+			finishRenderJob(job, certificate, err)
+		}
+	}
+}
+
+// changes the state of a render-job
+func setRenderJobStatus(job *RenderJob, status string) {
+	renderJobs.Lock()
+	defer renderJobs.Unlock()
+
+	job.Status = status
+}
+
+// stores the result of a render-job and wakes up the waiting callers
+func finishRenderJob(job *RenderJob, certificate CertificateData, err error) {
+	renderJobs.Lock()
+	defer renderJobs.Unlock()
+
+	job.certificate = certificate
+	job.Finished = time.Now().Unix()
+
+	if err != nil {
+		job.Status = jobFailed
+		job.Error = err.Error()
+
+		logger.Error().Msgf("render-job %q for %q failed: %v", job.Id, job.Mid, err)
+	} else {
+		job.Status = jobDone
+	}
+
+	close(job.done)
+}
+
+// queues a certificate for rendering, returns errRenderQueueFull if all workers are busy and the queue is full
+func submitRenderJob(certificate CertificateData) (*RenderJob, error) {
+	id, err := randomToken(16)
+
+	if err != nil {
+		return nil, err
+	}
+
+	job := &RenderJob{
+		Id:          id,
+		Mid:         certificate.Reservation.Mid,
+		Status:      jobQueued,
+		Created:     time.Now().Unix(),
+		certificate: certificate,
+		done:        make(chan struct{}),
+	}
+
+	renderJobs.Lock()
+	defer renderJobs.Unlock()
+
+	// forget the expired jobs
+	expired := time.Now().Add(-renderJobExpiration).Unix()
+
+	for jobId, oldJob := range renderJobs.jobs {
+		if oldJob.Finished != 0 && oldJob.Finished < expired {
+			delete(renderJobs.jobs, jobId)
+		}
+	}
+
+	select {
+	case renderJobs.queue <- job:
+		renderJobs.jobs[id] = job
+
+		return job, nil
+	default:
+		return nil, errRenderQueueFull
+	}
+}
+
+// renders a certificate with the worker-pool and waits for the result
+func renderCertificate(certificate CertificateData) (CertificateData, error) {
+	if job, err := submitRenderJob(certificate); err != nil {
+		return certificate, err
+	} else {
+		<-job.done
+
+		if job.Status == jobFailed {
+			return job.certificate, errors.New(job.Error)
+		} else {
+			return job.certificate, nil
+		}
+	}
+}
+
+// renders several certificates with the worker-pool, keeping their order
+func renderCertificates(certificates []CertificateData) ([]CertificateData, error) {
+	results := make([]CertificateData, len(certificates))
+	errs := make([]error, len(certificates))
+
+	// submit only as many jobs at once as there are workers, so the queue stays free for other requests
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range config.Certificate.Workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ii := range next {
+				results[ii], errs[ii] = renderCertificate(certificates[ii])
+			}
+		}()
+	}
+
+	for ii := range certificates {
+		next <- ii
+	}
+
+	close(next)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// returns a copy of a render-job or nil if it doesn't exist
+func getRenderJob(id string) *RenderJob {
+	renderJobs.Lock()
+	defer renderJobs.Unlock()
+
+	if job, ok := renderJobs.jobs[id]; !ok {
+		return nil
+	} else {
+		jobCopy := *job
+
+		return &jobCopy
+	}
+}
+
+// handles get-requests for the state of a render-job
+func getJobs(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if job := getRenderJob(c.Params("id")); job == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "job doesn't exist"
+
+		logger.Info().Msgf("render-job %q doesn't exist", c.Params("id"))
+	} else {
+		response.Data = job
+	}
+
+	return response
+}
+
+// handles get-requests for the pdf of a finished render-job
+func getJobsResult(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if job := getRenderJob(c.Params("id")); job == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "job doesn't exist"
+
+		logger.Info().Msgf("render-job %q doesn't exist", c.Params("id"))
+	} else if job.Status == jobFailed {
+		response.Status = fiber.StatusInternalServerError
+		response.Message = "job failed"
+	} else if job.Status != jobDone {
+		response.Status = fiber.StatusConflict
+		response.Message = "job isn't finished yet"
+	} else {
+		c.Attachment(job.certificate.fileName())
+		c.SendFile(job.certificate.PDFFile)
+	}
+
+	return response
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// orientations of the elements in a layout-section
+const (
+	orientationPortrait  = "portrait"
+	orientationLandscape = "landscape"
+)
+
+// maximum length of the element-ids, limited by the database-columns
+const maxElementIDLength = 16
+
+// position of an element or an unavailable slot in the layout
+type LayoutSlot struct {
+	Mid    string  `json:"mid,omitempty"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// section of the layout sent to the client
+type LayoutSection struct {
+	ID          string       `json:"id"`
+	Type        string       `json:"type"`
+	Class       string       `json:"class,omitempty"`
+	Elements    []LayoutSlot `json:"elements"`
+	Unavailable []LayoutSlot `json:"unavailable"`
+}
+
+// layout of all elements with their positions
+type Layout struct {
+	Width      float64         `json:"width"`
+	Height     float64         `json:"height"`
+	Background string          `json:"background,omitempty"`
+	Sections   []LayoutSection `json:"sections"`
+	// types of the elements by their id
+	types map[string]string
+}
+
+// validates the layout and calculates the positions of the elements
+func parseLayout(layoutYaml LayoutYaml, elementTypes map[string]ElementTypeYaml) (Layout, error) {
+	layout := Layout{
+		Width:      layoutYaml.Width,
+		Height:     layoutYaml.Height,
+		Background: layoutYaml.Background,
+		Sections:   make([]LayoutSection, len(layoutYaml.Sections)),
+		types:      map[string]string{},
+	}
+
+	if layout.Width <= 0 || layout.Height <= 0 {
+		return Layout{}, fmt.Errorf("invalid size")
+	}
+
+	for ii, sectionYaml := range layoutYaml.Sections {
+		if _, ok := elementTypes[sectionYaml.Type]; !ok {
+			return Layout{}, fmt.Errorf("unknown element-type %q of section %q", sectionYaml.Type, sectionYaml.ID)
+		} else if sectionYaml.ElementWidth <= 0 || sectionYaml.ElementHeight <= 0 || sectionYaml.Gap < 0 {
+			return Layout{}, fmt.Errorf("invalid element-size or gap of section %q", sectionYaml.ID)
+		}
+
+		width, height := sectionYaml.ElementWidth, sectionYaml.ElementHeight
+
+		switch sectionYaml.Orientation {
+		case orientationPortrait:
+		case orientationLandscape:
+			width, height = height, width
+		default:
+			return Layout{}, fmt.Errorf("invalid orientation %q of section %q", sectionYaml.Orientation, sectionYaml.ID)
+		}
+
+		section := LayoutSection{
+			ID:          sectionYaml.ID,
+			Type:        sectionYaml.Type,
+			Class:       sectionYaml.Class,
+			Elements:    []LayoutSlot{},
+			Unavailable: []LayoutSlot{},
+		}
+
+		for row, rowYaml := range sectionYaml.Rows {
+			if rowYaml.Prefix == "" || rowYaml.Columns <= 0 || rowYaml.Offset < 0 {
+				return Layout{}, fmt.Errorf("invalid row %d of section %q", row+1, sectionYaml.ID)
+			} else if !strings.Contains(rowYaml.Prefix, "-") {
+				// the part after the dash is the id shown to the sponsors
+				return Layout{}, fmt.Errorf(`prefix %q of row %d of section %q doesn't contain a "-"`, rowYaml.Prefix, row+1, sectionYaml.ID)
+			} else if length := len(fmt.Sprintf("%s%d", rowYaml.Prefix, rowYaml.Columns)); length > maxElementIDLength {
+				return Layout{}, fmt.Errorf("element-ids of row %d of section %q are longer than %d characters", row+1, sectionYaml.ID, maxElementIDLength)
+			}
+
+			for slot := 1; slot <= rowYaml.Columns; slot++ {
+				position := LayoutSlot{
+					Mid:    fmt.Sprintf("%s%d", rowYaml.Prefix, slot),
+					X:      sectionYaml.X + float64(rowYaml.Offset+slot-1)*(width+sectionYaml.Gap),
+					Y:      sectionYaml.Y + float64(row)*(height+sectionYaml.Gap),
+					Width:  width,
+					Height: height,
+				}
+
+				if slices.Contains(rowYaml.Unavailable, slot) {
+					position.Mid = ""
+
+					section.Unavailable = append(section.Unavailable, position)
+				} else if _, ok := layout.types[position.Mid]; ok {
+					return Layout{}, fmt.Errorf("duplicate element %q", position.Mid)
+				} else {
+					layout.types[position.Mid] = sectionYaml.Type
+
+					section.Elements = append(section.Elements, position)
+				}
+			}
+		}
+
+		layout.Sections[ii] = section
+	}
+
+	return layout, nil
+}
+
+// handles get-requests for the layout of the elements
+func getLayout(c *fiber.Ctx) responseMessage {
+	return responseMessage{Data: config.Layout}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+)
+
+// returns a layout with a single landscape-section and applies the modification
+func testLayout(modify func(layout *LayoutYaml)) LayoutYaml {
+	layout := LayoutYaml{
+		Width:  100,
+		Height: 50,
+		Sections: []LayoutSectionYaml{{
+			ID:            "roof",
+			Type:          "pv-roof",
+			X:             10,
+			Y:             5,
+			Orientation:   orientationLandscape,
+			ElementWidth:  2,
+			ElementHeight: 4,
+			Gap:           1,
+			Rows: []LayoutRowYaml{
+				{Prefix: "pv-a", Columns: 3},
+				{Prefix: "pv-b", Columns: 2, Offset: 1, Unavailable: []int{2}},
+			},
+		}},
+	}
+
+	if modify != nil {
+		modify(&layout)
+	}
+
+	return layout
+}
+
+func TestParseLayout(t *testing.T) {
+	elementTypes := map[string]ElementTypeYaml{"pv-roof": {ID: "pv-roof"}}
+
+	layout, err := parseLayout(testLayout(nil), elementTypes)
+	if err != nil {
+		t.Fatalf("can't parse layout: %v", err)
+	}
+
+	section := layout.Sections[0]
+
+	expected := []LayoutSlot{
+		{Mid: "pv-a1", X: 10, Y: 5, Width: 4, Height: 2},
+		{Mid: "pv-a2", X: 15, Y: 5, Width: 4, Height: 2},
+		{Mid: "pv-a3", X: 20, Y: 5, Width: 4, Height: 2},
+		{Mid: "pv-b1", X: 15, Y: 8, Width: 4, Height: 2},
+	}
+
+	if len(section.Elements) != len(expected) {
+		t.Fatalf("layout has the elements %+v, expected %+v", section.Elements, expected)
+	}
+
+	for ii, slot := range section.Elements {
+		if slot != expected[ii] {
+			t.Errorf("element %d is %+v, expected %+v", ii, slot, expected[ii])
+		}
+	}
+
+	if len(section.Unavailable) != 1 || section.Unavailable[0] != (LayoutSlot{X: 20, Y: 8, Width: 4, Height: 2}) {
+		t.Errorf("layout has the unavailable slots %+v, expected the second slot of row b", section.Unavailable)
+	}
+
+	if layout.types["pv-b1"] != "pv-roof" || len(layout.types) != 4 {
+		t.Errorf("layout has the element-types %v", layout.types)
+	}
+
+	invalid := map[string]func(layout *LayoutYaml){
+		"invalid size":          func(layout *LayoutYaml) { layout.Width = 0 },
+		"unknown element-type":  func(layout *LayoutYaml) { layout.Sections[0].Type = "unknown" },
+		"invalid element-size":  func(layout *LayoutYaml) { layout.Sections[0].ElementHeight = 0 },
+		"negative gap":          func(layout *LayoutYaml) { layout.Sections[0].Gap = -1 },
+		"invalid orientation":   func(layout *LayoutYaml) { layout.Sections[0].Orientation = "diagonal" },
+		"empty prefix":          func(layout *LayoutYaml) { layout.Sections[0].Rows[0].Prefix = "" },
+		"prefix without dash":   func(layout *LayoutYaml) { layout.Sections[0].Rows[0].Prefix = "pva" },
+		"too long element-ids":  func(layout *LayoutYaml) { layout.Sections[0].Rows[0].Prefix = "pv-kirchendach-a" },
+		"row without columns":   func(layout *LayoutYaml) { layout.Sections[0].Rows[0].Columns = 0 },
+		"negative offset":       func(layout *LayoutYaml) { layout.Sections[0].Rows[0].Offset = -1 },
+		"duplicate element-ids": func(layout *LayoutYaml) { layout.Sections[0].Rows[1].Prefix = "pv-a" },
+	}
+
+	for name, modify := range invalid {
+		if _, err := parseLayout(testLayout(modify), elementTypes); err == nil {
+			t.Errorf("%s: layout was accepted", name)
+		}
+	}
+}
+
+func TestGetElementID(t *testing.T) {
+	for mid, id := range map[string]string{"pv-a12": "A12", "wr-1": "1", "pv-kd-3": "KD-3", "legacy": "LEGACY"} {
+		if result := getElementID(mid); result != id {
+			t.Errorf("getElementID(%q) = %q, expected %q", mid, result, id)
+		}
+	}
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	templateHTML "html/template"
+	"math"
+	netMail "net/mail"
 	"os"
 	"reflect"
+	"strconv"
 	"text/template"
+	"unicode/utf8"
 )
 
 func strucToMap(data any) (map[string]any, error) {
@@ -72,3 +78,70 @@ func parseHTMLTemplate(pth string, vals any) (string, error) {
 		return buf.String(), err
 	}
 }
+
+// creates a random hex-token from n bytes
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// checks wether a string is a valid e-mail-address
+func validateMail(address string) bool {
+	if parsed, err := netMail.ParseAddress(address); err != nil {
+		return false
+	} else {
+		// reject addresses with display-names
+		return parsed.Address == address
+	}
+}
+
+// returns nil for an empty string, so it is stored as NULL
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	} else {
+		return &s
+	}
+}
+
+// shortens a string to at most n bytes without splitting characters
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// formats an amount of euros the german way, e.g. "1.250 €" or "12,50 €"
+func formatEuro(amount float64) string {
+	cents := int64(math.Round(amount * 100))
+
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	// group the euros by thousands
+	euros := strconv.FormatInt(cents/100, 10)
+
+	for ii := len(euros) - 3; ii > 0; ii -= 3 {
+		euros = euros[:ii] + "." + euros[ii:]
+	}
+
+	if cents%100 == 0 {
+		return fmt.Sprintf("%s%s €", sign, euros)
+	} else {
+		return fmt.Sprintf("%s%s,%02d €", sign, euros, cents%100)
+	}
+}
@@ -1,23 +1,28 @@
 package main
 
 import (
-	"time"
-
-	mail "github.com/xhit/go-simple-mail/v2"
+	"fmt"
 )
 
-var mailServer *mail.SMTPServer
-
-func init() {
-	mailServer = mail.NewSMTPClient()
-
-	mailServer.Host = config.Mail.Server
-	mailServer.Port = config.Mail.Port
-	mailServer.Encryption = mail.EncryptionSSLTLS
+// transport for the rendered mails
+type Mailer interface {
+	// delivers a rfc822-formatted message
+	send(from string, recipients []string, message []byte) error
+}
 
-	mailServer.Username = config.Mail.User
-	mailServer.Password = config.Mail.Password
+// transport for the mails from the outbox
+var mailer Mailer
 
-	mailServer.ConnectTimeout = 10 * time.Second
-	mailServer.SendTimeout = 10 * time.Second
+// creates the mail-transport selected in the config
+func newMailer() (Mailer, error) {
+	switch config.Mail.Transport {
+	case "smtp":
+		return newSMTPMailer()
+	case "sendmail":
+		return newSendmailMailer()
+	case "file":
+		return newFileMailer()
+	default:
+		return nil, fmt.Errorf("unknown mail-transport %q", config.Mail.Transport)
+	}
 }
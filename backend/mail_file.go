@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// writes the mails as .eml-files into a maildir, e.g. for development and tests
+type fileMailer struct {
+	directory string
+}
+
+func newFileMailer() (*fileMailer, error) {
+	if config.Mail.Directory == "" {
+		return nil, fmt.Errorf(`"mail.directory" is required for the file-transport`)
+	}
+
+	// create the maildir-structure
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(path.Join(config.Mail.Directory, dir), 0o750); err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileMailer{directory: config.Mail.Directory}, nil
+}
+
+func (m *fileMailer) send(from string, recipients []string, message []byte) error {
+	token, err := randomToken(8)
+
+	if err != nil {
+		return err
+	}
+
+	fileName := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), token)
+
+	// write the file to "tmp" first, so readers of "new" never see partial mails
+	tmpFile := path.Join(m.directory, "tmp", fileName)
+
+	if err := os.WriteFile(tmpFile, message, 0o640); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, path.Join(m.directory, "new", fileName))
+}
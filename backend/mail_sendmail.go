@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// pipes the mails into a local sendmail-binary
+type sendmailMailer struct {
+	executable string
+}
+
+func newSendmailMailer() (*sendmailMailer, error) {
+	if _, err := os.Stat(config.Mail.Sendmail); err != nil {
+		return nil, fmt.Errorf("can't find sendmail: %v", err)
+	}
+
+	return &sendmailMailer{executable: config.Mail.Sendmail}, nil
+}
+
+func (m *sendmailMailer) send(from string, recipients []string, message []byte) error {
+	// don't treat a single dot as the end of the message and set the envelope-sender
+	command := exec.Command(m.executable, append([]string{"-i", "-f", from, "--"}, recipients...)...)
+	command.Stdin = bytes.NewReader(message)
+
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail failed: %v: %s", err, output)
+	}
+
+	return nil
+}
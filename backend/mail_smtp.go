@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// sends the mails to a smtp-server
+type smtpMailer struct {
+	server *mail.SMTPServer
+}
+
+func newSMTPMailer() (*smtpMailer, error) {
+	server := mail.NewSMTPClient()
+
+	server.Host = config.Mail.Server
+	server.Port = config.Mail.Port
+
+	switch config.Mail.Encryption {
+	case "none":
+		server.Encryption = mail.EncryptionNone
+	case "starttls":
+		server.Encryption = mail.EncryptionSTARTTLS
+	case "tls":
+		server.Encryption = mail.EncryptionSSLTLS
+	default:
+		return nil, fmt.Errorf("unknown mail-encryption %q", config.Mail.Encryption)
+	}
+
+	server.Username = config.Mail.User
+	server.Password = config.Mail.Password
+
+	server.ConnectTimeout = 10 * time.Second
+	server.SendTimeout = 10 * time.Second
+
+	return &smtpMailer{server: server}, nil
+}
+
+func (m *smtpMailer) send(from string, recipients []string, message []byte) error {
+	if mailClient, err := m.server.Connect(); err != nil {
+		return err
+	} else {
+		defer mailClient.Close()
+
+		return mail.SendMessage(from, recipients, string(message), mailClient)
+	}
+}
@@ -1,27 +1,20 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
-	"reflect"
-	"slices"
-	"strconv"
+	templateHTML "html/template"
+	"net/url"
 	"strings"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/patrickmn/go-cache"
 	mail "github.com/xhit/go-simple-mail/v2"
 	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
-// connection to database
-var db *sql.DB
-
 // cache for database
 var dbCache *cache.Cache
 
@@ -32,196 +25,6 @@ type responseMessage struct {
 	Data    any
 }
 
-// query the database
-func dbSelect[T any](table string, where string, args ...any) ([]T, error) {
-	// validate columns against struct T
-	tType := reflect.TypeOf(new(T)).Elem()
-	columns := make([]string, tType.NumField())
-
-	validColumns := make(map[string]any)
-	for ii := 0; ii < tType.NumField(); ii++ {
-		field := tType.Field(ii)
-		validColumns[strings.ToLower(field.Name)] = struct{}{}
-		columns[ii] = strings.ToLower(field.Name)
-	}
-
-	for _, col := range columns {
-		if _, ok := validColumns[strings.ToLower(col)]; !ok {
-			return nil, fmt.Errorf("invalid column: %s for struct type %T", col, new(T))
-		}
-	}
-
-	// create the query
-	completeQuery := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table)
-
-	if where != "" && where != "*" {
-		completeQuery = fmt.Sprintf("%s WHERE %s", completeQuery, where)
-	}
-
-	var rows *sql.Rows
-	var err error
-
-	if len(args) > 0 {
-		db.Ping()
-
-		rows, err = db.Query(completeQuery, args...)
-	} else {
-		db.Ping()
-
-		rows, err = db.Query(completeQuery)
-	}
-
-	if err != nil {
-		logger.Error().Msgf("database access failed with error %v", err)
-
-		return nil, err
-	}
-
-	defer rows.Close()
-	results := []T{}
-
-	title := cases.Title(language.Und)
-
-	for rows.Next() {
-		var lineResult T
-
-		scanArgs := make([]any, len(columns))
-		v := reflect.ValueOf(&lineResult).Elem()
-
-		for ii, col := range columns {
-			colTitle := title.String(col)
-
-			field := v.FieldByName(colTitle)
-
-			if field.IsValid() && field.CanSet() {
-				scanArgs[ii] = field.Addr().Interface()
-			} else {
-				logger.Warn().Msgf("Field %s not found in struct %T", col, lineResult)
-				scanArgs[ii] = new(any) // save dummy value
-			}
-		}
-
-		// scan the row into the struct
-		if err := rows.Scan(scanArgs...); err != nil {
-			logger.Warn().Msgf("Scan-error: %v", err)
-
-			return nil, err
-		}
-
-		results = append(results, lineResult)
-	}
-
-	if err := rows.Err(); err != nil {
-		logger.Error().Msgf("rows-error: %v", err)
-		return nil, err
-	} else {
-		return results, nil
-	}
-}
-
-// insert data intot the databse
-func dbInsert(table string, vals any) error {
-	// extract columns from vals
-	v := reflect.ValueOf(vals)
-	t := v.Type()
-
-	columns := make([]string, t.NumField())
-	values := make([]any, t.NumField())
-
-	for ii := 0; ii < t.NumField(); ii++ {
-		fieldValue := v.Field(ii)
-
-		field := t.Field(ii)
-
-		columns[ii] = strings.ToLower(field.Name)
-		values[ii] = fieldValue.Interface()
-	}
-
-	placeholders := strings.Repeat(("?, "), len(columns))
-	placeholders = strings.TrimSuffix(placeholders, ", ")
-
-	completeQuery := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
-
-	_, err := db.Exec(completeQuery, values...)
-
-	return err
-}
-
-// update data in the database
-func dbUpdate(table string, set, where any) error {
-	setV := reflect.ValueOf(set)
-	setT := setV.Type()
-
-	setColumns := make([]string, setT.NumField())
-	setValues := make([]any, setT.NumField())
-
-	for ii := 0; ii < setT.NumField(); ii++ {
-		fieldValue := setV.Field(ii)
-
-		field := setT.Field(ii)
-
-		setColumns[ii] = strings.ToLower(field.Name) + " = ?"
-		setValues[ii] = fieldValue.Interface()
-	}
-
-	whereV := reflect.ValueOf(where)
-	whereT := whereV.Type()
-
-	whereColumns := make([]string, whereT.NumField())
-	whereValues := make([]any, whereT.NumField())
-
-	for ii := 0; ii < whereT.NumField(); ii++ {
-		fieldValue := whereV.Field(ii)
-
-		// skip empty (zero) values
-		if !fieldValue.IsZero() {
-			field := whereT.Field(ii)
-
-			whereColumns[ii] = strings.ToLower(field.Name) + " = ?"
-			whereValues[ii] = fmt.Sprint(fieldValue.Interface())
-		}
-	}
-
-	sets := strings.Join(setColumns, ", ")
-	wheres := strings.Join(whereColumns, " AND ")
-
-	placeholderValues := append(setValues, whereValues...)
-
-	completeQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, sets, wheres)
-
-	_, err := db.Exec(completeQuery, placeholderValues...)
-
-	return err
-}
-
-// remove data from the database
-func dbDelete(table string, vals any) error {
-	// extract columns from vals
-	v := reflect.ValueOf(vals)
-	t := v.Type()
-
-	columns := make([]string, t.NumField())
-	values := make([]any, t.NumField())
-
-	for ii := 0; ii < t.NumField(); ii++ {
-		fieldValue := v.Field(ii)
-
-		// skip empty (zero) values
-		if !fieldValue.IsZero() {
-			field := t.Field(ii)
-
-			columns[ii] = strings.ToLower(field.Name) + " = ?"
-			values[ii] = fmt.Sprint(fieldValue.Interface())
-		}
-	}
-
-	completeQuery := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(columns, ", "))
-
-	_, err := db.Exec(completeQuery, values...)
-
-	return err
-}
-
 // answer the client request with the response-message
 func (result responseMessage) send(c *fiber.Ctx) error {
 	// if the status-code is in the error-region, return an error
@@ -232,6 +35,9 @@ func (result responseMessage) send(c *fiber.Ctx) error {
 		} else {
 			return fiber.NewError(result.Status)
 		}
+	} else if c.Response().IsBodyStream() && result.Status == 0 {
+		// leave streamed downloads alone, checking their body would read the whole stream into memory
+		return nil
 	} else {
 		// if there is data, send it as JSON
 		if result.Data != nil {
@@ -248,8 +54,14 @@ func (result responseMessage) send(c *fiber.Ctx) error {
 
 // payload of the JSON webtoken
 type JWTPayload struct {
-	Uid int `json:"uid"`
-	Tid int `json:"tid"`
+	Uid  int    `json:"uid"`
+	Tid  int    `json:"tid"`
+	Role string `json:"role"`
+	// id of the JWT, identifies the session in the database
+	Jti string `json:"-"`
+	// id and scopes of the api-token, if the request isn't authenticated by a session
+	Token  string   `json:"-"`
+	Scopes []string `json:"-"`
 }
 
 // complete JSON webtoken
@@ -259,29 +71,23 @@ type JWT struct {
 }
 
 // extracts the json webtoken from the request
-//
-// @returns (uID, tID, error)
-func extractJWT(c *fiber.Ctx) (int, int, error) {
+func extractJWT(c *fiber.Ctx) (JWTPayload, error) {
 	// get the session-cookie
 	cookie := c.Cookies("session")
 
-	token, err := jwt.ParseWithClaims(cookie, &JWT{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected JWT signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(config.ClientSession.JwtSignature), nil
-	})
+	token, err := jwt.ParseWithClaims(cookie, &JWT{}, config.verificationKey)
 
 	if err != nil {
-		return -1, -1, err
+		return JWTPayload{}, err
 	}
 
 	// extract the claims from the JWT
 	if claims, ok := token.Claims.(*JWT); ok && token.Valid {
-		return claims.CustomClaims.Uid, claims.CustomClaims.Tid, nil
+		claims.CustomClaims.Jti = claims.ID
+
+		return claims.CustomClaims, nil
 	} else {
-		return -1, -1, fmt.Errorf("invalid JWT")
+		return JWTPayload{}, fmt.Errorf("invalid JWT")
 	}
 }
 
@@ -304,66 +110,77 @@ func setSessionCookie(c *fiber.Ctx, jwt *string) {
 }
 
 // checks wether the request is from a valid user
-func checkUser(c *fiber.Ctx) (bool, error) {
-	uid, tid, err := extractJWT(c)
+//
+// @returns (session or nil, error)
+func checkUser(c *fiber.Ctx) (*JWTPayload, error) {
+	// scripts authenticate with an api-token instead of the session-cookie
+	if token, ok := bearerToken(c); ok {
+		return checkAPIToken(c, token)
+	}
+
+	session, err := extractJWT(c)
 
 	if err != nil {
-		return false, nil
+		return nil, nil
 	}
 
 	// retrieve the user from the database
-	response, err := dbSelect[UserDB]("users", "uid = ? LIMIT 1", uid)
+	user, err := store.GetUser(session.Uid)
 
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// if exactly one user came back and the tID is valid, the user is authorized
-	if len(response) == 1 && response[0].Tid == tid {
-		// reset the expiration of the cookie
-		setSessionCookie(c, nil)
-
-		return true, err
-	} else {
-		return false, err
+	// the user has to exist and the tID has to be valid
+	if user == nil || user.Tid != session.Tid {
+		return nil, nil
 	}
-}
 
-// checks wether the request is from the admin
-func checkAdmin(c *fiber.Ctx) (bool, error) {
-	uid, tid, err := extractJWT(c)
-
-	if err != nil {
-		return false, err
+	// the session has to be still present in the database
+	if dbSession, err := store.GetSession(session.Jti); err != nil || dbSession == nil || dbSession.Uid != user.Uid {
+		return nil, err
+	} else if err := store.TouchSession(session.Jti, c.IP(), time.Now(), sessionTouchInterval); err != nil {
+		return nil, err
 	}
 
-	// retrieve the user from the database
-	response, err := dbSelect[UserDB]("users", "uid = ? LIMIT 1", uid)
-
-	if err != nil {
-		return false, err
-	}
+	// reset the expiration of the cookie
+	setSessionCookie(c, nil)
 
-	// if exactly one user came back and its name is "admin", the user is the admin
-	if len(response) != 1 {
-		return false, fmt.Errorf("user doesn't exist")
-	} else {
-		return response[0].Name == "admin" && response[0].Tid == tid, err
-	}
+	return &session, nil
 }
 
 // information about an element in the database
 type ElementDB struct {
-	Mid         string  `json:"mid"`
-	Name        string  `json:"name"`
-	Reservation *string `json:"reservation"`
-	Mail        *string `json:"mail"`
+	Mid          string  `json:"mid"`
+	Name         string  `json:"name"`
+	Reservation  *string `json:"reservation"`
+	Mail         *string `json:"mail"`
+	Confirmation *string `json:"-"`
+	// amounts in cents
+	Due       *int64  `json:"due"`
+	Received  int64   `json:"received"`
+	Paid      *string `json:"paid"`
+	Method    *string `json:"method"`
+	Reference *string `json:"reference"`
+	// creditor-reference for the transfer of the sponsor
+	Remittance *string `json:"remittance"`
+	// postal address of the sponsor for the donation-receipts
+	Address *string `json:"address"`
 }
 
 type ElementDBNoReservation struct {
-	Mid  string  `json:"mid"`
-	Name string  `json:"name"`
-	Mail *string `json:"mail"`
+	Mid       string  `json:"mid"`
+	Name      string  `json:"name"`
+	Mail      *string `json:"mail"`
+	Due       *int64  `json:"due"`
+	Received  int64   `json:"received"`
+	Paid      *string `json:"paid"`
+	Method    *string `json:"method"`
+	Reference *string `json:"reference"`
+	// creditor-reference for the transfer of the sponsor
+	Remittance *string `json:"remittance"`
+	// postal address of the sponsor for the donation-receipts
+	Address *string `json:"address"`
 }
 
 // client-data of the reserved elements
@@ -379,20 +196,29 @@ type ElementsCache struct {
 
 // caches the elements from the database
 func cacheElements() error {
-	if res, err := dbSelect[ElementDB]("elements", "*"); err != nil {
+	if res, err := store.GetElements(); err != nil {
 		return err
 	} else {
 		// delete all expired reservations
-		var expiredElements []any
+		var expiredElements []string
 		expirationDate := time.Now().Add(-config.Reservation.Expiration)
 
 		takenElements := make(map[string]string)
 		reservedElements := []string{}
 
+		// pending reservations expire earlier
+		confirmationExpirationDate := time.Now().Add(-config.Reservation.ConfirmationExpiration)
+
 		for _, element := range res {
 			if element.Reservation != nil {
+				elementExpirationDate := expirationDate
+
+				if element.Confirmation != nil {
+					elementExpirationDate = confirmationExpirationDate
+				}
+
 				if reservationDate, err := time.Parse(time.DateTime, *element.Reservation); err == nil {
-					if reservationDate.Sub(expirationDate) < 0 {
+					if reservationDate.Sub(elementExpirationDate) < 0 {
 						expiredElements = append(expiredElements, element.Mid)
 
 						continue
@@ -407,7 +233,7 @@ func cacheElements() error {
 
 		if len(expiredElements) > 0 {
 			// remove the expired elements from the database
-			if _, err := db.Exec(fmt.Sprintf("DELETE FROM elements WHERE mid IN (%s?)", strings.Repeat("?, ", len(expiredElements)-1)), expiredElements...); err != nil {
+			if err := store.DeleteElements(expiredElements...); err != nil {
 				logger.Error().Msgf("can't remove expired elements from database: %v", err)
 
 				return err
@@ -457,36 +283,19 @@ func getElements(c *fiber.Ctx) responseMessage {
 	return response
 }
 
-// regex to match valid element-names
-func isValidMid(element string) (bool, error) {
-	if results := config.MidRegex.FindStringSubmatch(element); results == nil {
-		return false, nil
-	} else {
-		// check wether the descriptor-part is valid
-		if rng, ok := config.ValidateElements.ValidElements[results[1]]; !ok {
-			return false, nil
-
-			// try to parse the mid-number
-		} else if n, err := strconv.Atoi(results[2]); err != nil {
-			return false, err
-		} else {
-			return rng.From <= n && n <= rng.To, nil
-		}
-	}
-}
-
 // handles post-requests for reserving new elements
 func postElements(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
 	body := struct {
-		Name string
-		Mail string
+		Name    string
+		Mail    string
+		Address string
 	}{}
 
 	mid := c.Query("mid")
 
-	if ok, err := isValidMid(mid); err != nil || !ok {
+	if !isValidMid(mid) {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "invalid mID"
 
@@ -495,65 +304,61 @@ func postElements(c *fiber.Ctx) responseMessage {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "invalid message-body"
 
-		logger.Warn().Msg(`body can't be parsed as "struct{ name string mail string}"`)
-	} else {
-		elements, found := dbCache.Get("elements")
-
-		if !found {
-			if err := cacheElements(); err != nil {
-				response.Status = fiber.StatusInternalServerError
-				response.Message = "can't get elements"
-
-				logger.Error().Msgf("can't get elements from database: %v", err)
-			} else if elements, found = dbCache.Get("elements"); !found {
-				response.Status = fiber.StatusInternalServerError
-				response.Message = "can't get elements"
+		logger.Warn().Msg(`body can't be parsed as "struct{ name string mail string address string }"`)
+	} else if len(body.Address) > addressLength {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "address is too long"
 
-				logger.Error().Msg("can't get 'elements' from cache")
-			}
-		}
+		logger.Info().Msgf("can't reserve element: address is too long")
+	} else if confirmation, err := randomToken(16); err != nil {
+		response.Status = fiber.StatusInternalServerError
 
-		// if the status is still unset, there was no error
-		if response.Status == 0 {
-			// check wether the element already exists
-			if _, ok := elements.(ElementsCache).Taken[mid]; ok {
-				response.Status = fiber.StatusBadRequest
-				response.Message = "element is already taken"
+		logger.Error().Msgf("can't create reservation-confirmation: %v", err)
+	} else if remittance, err := newRemittanceReference(mid); err != nil {
+		response.Status = fiber.StatusInternalServerError
 
-				logger.Info().Msgf("element %q is already taken", mid)
+		logger.Error().Msgf("can't create remittance-reference: %v", err)
+	} else if err := store.ClaimElement(ElementDBNoReservation{Mid: mid, Name: body.Name, Mail: &body.Mail, Due: elementPrice(mid), Remittance: &remittance, Address: optionalString(strings.TrimSpace(body.Address))}, confirmation, config.Reservation.Expiration, config.Reservation.ConfirmationExpiration); errors.Is(err, errElementUnavailable) {
+		response.Status = fiber.StatusConflict
+		response.Message = "element is already taken or reserved"
 
-				return response
-			} else if slices.Contains(elements.(ElementsCache).Reserved, mid); ok {
-				response.Status = fiber.StatusBadRequest
-				response.Message = "element is currently reserved"
+		logger.Info().Msgf("element %q is already taken or reserved", mid)
+	} else if err != nil {
+		response.Status = fiber.StatusInternalServerError
+		response.Message = "error while writing reservation to database"
 
-				logger.Info().Msgf("element %q is currently reserved", mid)
-			}
+		logger.Error().Msgf("can't write reservation to database: %v", err)
+	} else {
+		// clear the current cache
+		dbCache.Delete("elements")
+
+		// send the reservation e-mail with the confirmation-link
+		data := ReservationData{
+			Mail:       body.Mail,
+			Mid:        mid,
+			Name:       body.Name,
+			Remittance: remittance,
+		}
 
-			// send the reservation e-mail
-			data := ReservationData{
-				Mail: body.Mail,
-				Mid:  mid,
-				Name: body.Name,
-			}
+		if token, err := config.signConfirmationJWT(mid, confirmation); err != nil {
+			response.Status = fiber.StatusInternalServerError
 
-			if err := data.sendReservationEmail(); err != nil {
-				logger.Error().Msgf("can't send reservation-mail: %v", err)
-			} else {
-				// clear the current cache
-				dbCache.Delete("elements")
+			logger.Error().Msgf("can't create confirmation-link: %v", err)
+		} else if err := data.sendReservationEmail(confirmationLink(token)); err != nil {
+			response.Status = fiber.StatusInternalServerError
+			response.Message = "error while sending reservation-mail"
 
-				// write the data to the database
-				if err := dbInsert("elements", ElementDBNoReservation{Mid: mid, Name: body.Name, Mail: &body.Mail}); err != nil {
-					response.Status = fiber.StatusInternalServerError
-					response.Message = "error while writing reservation to database"
+			logger.Error().Msgf("can't queue reservation-mail: %v", err)
+		} else {
+			response = getElements(c)
 
-					logger.Error().Msgf("can't write reservation to database: %v", err)
-				} else {
-					response = getElements(c)
+			logger.Debug().Msgf("reserved element %q pending confirmation", mid)
+		}
 
-					logger.Debug().Msgf("reserved element %q", mid)
-				}
+		// give the element free again, since the sponsor didn't get the mail
+		if response.Status >= 400 {
+			if err := store.ReleaseElement(mid); err != nil {
+				logger.Error().Msgf("can't release element %q: %v", mid, err)
 			}
 		}
 	}
@@ -561,50 +366,104 @@ func postElements(c *fiber.Ctx) responseMessage {
 	return response
 }
 
-func getElementType(mid string) string {
-	switch strings.Split(mid, "-")[0] {
-	case "pv":
-		return "PV-Modul"
-	case "bs":
-		return "Batteriespeicher"
-	default:
-		return ""
-	}
+// creates the link to confirm a pending reservation
+func confirmationLink(token string) string {
+	return fmt.Sprintf("%s/api/elements/confirm?token=%s", strings.TrimSuffix(config.Server.URL, "/"), url.QueryEscape(token))
 }
 
-func getElementArticle(mid string) string {
-	switch strings.Split(mid, "-")[0] {
-	case "pv":
-		return "das"
-	case "bs":
-		return "den"
-	default:
-		return ""
+// handles the confirmation-link from the reservation-mail
+func getElementsConfirm(c *fiber.Ctx) responseMessage {
+	response := responseMessage{}
+
+	payload := ConfirmationPayload{}
+
+	if token, err := jwt.ParseWithClaims(c.Query("token"), &payload, config.verificationKey, jwt.WithAudience(confirmationAudience)); err != nil || !token.Valid {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid or expired confirmation-link"
+
+		logger.Info().Msgf("invalid reservation-confirmation: %v", err)
+	} else if ok, err := store.ConfirmReservation(payload.Mid, payload.Confirmation); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't confirm reservation for %q: %v", payload.Mid, err)
+	} else if !ok {
+		response.Status = fiber.StatusGone
+		response.Message = "reservation is already confirmed or expired"
+
+		logger.Info().Msgf("no pending reservation for %q", payload.Mid)
+	} else {
+		dbCache.Delete("elements")
+
+		response.Status = fiber.StatusOK
+		response.Message = "reservation confirmed"
+
+		logger.Info().Msgf("confirmed reservation for %q", payload.Mid)
 	}
+
+	return response
 }
 
 func getElementID(mid string) string {
-	return strings.ToUpper(strings.Split(mid, "-")[1])
+	// ids without a dash can only come from elements that were stored before the layout was validated
+	if _, id, ok := strings.Cut(mid, "-"); ok {
+		return strings.ToUpper(id)
+	} else {
+		return strings.ToUpper(mid)
+	}
 }
 
 type ReservationData struct {
 	Mail string
 	Mid  string
 	Name string
+	// creditor-reference for the transfer of the sponsor
+	Remittance string
+}
+
+// template-data of the reservation-mail
+type ReservationTemplateData struct {
+	SponsorshipTemplateData
+	ConfirmationLink string
+	// creditor-reference for the transfer
+	Reference   string
+	Amount      string
+	Beneficiary string
+	IBAN        string
+	BIC         string
+	// source of the inline giro-code-image, empty if no bank-account is configured
+	GiroCode templateHTML.URL
 }
 
-func (data ReservationData) sendReservationEmail() error {
+func (data ReservationData) sendReservationEmail(confirmationLink string) error {
 	email := mail.NewMSG()
 
-	templateData := SponsorshipTemplateData{}
+	amount := amountDue(data.Mid, nil)
+
+	templateData := ReservationTemplateData{
+		ConfirmationLink: confirmationLink,
+		Reference:        data.Remittance,
+		Amount:           formatCents(amount),
+		Beneficiary:      config.BankAccount.Beneficiary,
+		IBAN:             formatIBAN(config.BankAccount.IBAN),
+		BIC:              config.BankAccount.BIC,
+	}
 	templateData.populate(data.Mid, data.Name)
 
+	giroCode, err := giroCodePNG(amount, data.Remittance)
+	if err != nil {
+		return err
+	} else if giroCode != nil {
+		templateData.GiroCode = templateHTML.URL("cid:" + giroCodeName)
+	}
+
 	if subject, err := parseTemplate("templates/reservation_mail", templateData); err != nil {
 		return err
 	} else if bodyHTML, err := parseHTMLTemplate("templates/reservation_mail.html", templateData); err != nil {
 		return err
 	} else if bodyPlain, err := parseHTMLTemplate("templates/reservation_mail.txt", templateData); err != nil {
 		return err
+	} else if invoice, err := createInvoice(templateData, giroCode); err != nil {
+		return err
 	} else {
 		email.SetFrom(fmt.Sprintf("Klimaplus-Patenschaft <%s>", config.Mail.User)).AddTo(data.Mail).SetSubject(subject)
 
@@ -612,15 +471,22 @@ func (data ReservationData) sendReservationEmail() error {
 
 		email.AddAlternative(mail.TextHTML, bodyHTML)
 
-		if mailClient, err := mailServer.Connect(); err != nil {
-			logger.Fatal().Msgf("can't connect to to mail-server: %v", err)
-
-			return err
-		} else if err := email.Send(mailClient); err != nil {
-			return err
-		} else {
-			return nil
+		if giroCode != nil {
+			email.Attach(&mail.File{
+				Data:     giroCode,
+				Name:     giroCodeName,
+				MimeType: "image/png",
+				Inline:   true,
+			})
 		}
+
+		email.Attach(&mail.File{
+			Data:     invoice,
+			Name:     invoiceFileName(data.Mid),
+			MimeType: "application/pdf",
+		})
+
+		return enqueueMail(email, subject)
 	}
 }
 
@@ -628,55 +494,45 @@ func (data ReservationData) sendReservationEmail() error {
 func patchElements(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
-	if user, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check user: %v", err)
-	} else if !user {
-		response.Status = fiber.StatusUnauthorized
+	body := struct{ Name string }{}
 
-		logger.Info().Msg("request is not authorized as user")
-	} else {
-		body := struct{ Name string }{}
-
-		mid := c.Query("mid")
-		if ok, err := isValidMid(mid); err != nil || !ok {
-			response.Status = fiber.StatusBadRequest
-			response.Message = "invalid element name"
+	mid := c.Query("mid")
+	if !isValidMid(mid) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid element name"
 
-			logger.Info().Msgf("can't modify element: invalid element-name: %q", mid)
-		} else if err := c.BodyParser(&body); err != nil {
-			response.Status = fiber.StatusBadRequest
-			response.Message = "invalid message-body"
+		logger.Info().Msgf("can't modify element: invalid element-name: %q", mid)
+	} else if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid message-body"
 
-			logger.Warn().Msg(`body can't be parsed as "struct{ name string }"`)
-		} else {
-			// check wether the element already exists
-			if elements, found := dbCache.Get("elements"); found {
-				if _, ok := elements.(map[string]string)[mid]; !ok {
-					response.Status = fiber.StatusBadRequest
-					response.Message = "element is already reserved"
+		logger.Warn().Msg(`body can't be parsed as "struct{ name string }"`)
+	} else {
+		// check wether the element already exists
+		if elements, found := dbCache.Get("elements"); found {
+			if _, ok := elements.(map[string]string)[mid]; !ok {
+				response.Status = fiber.StatusBadRequest
+				response.Message = "element is already reserved"
 
-					logger.Info().Msgf("element %q is already reserved", mid)
+				logger.Info().Msgf("element %q is already reserved", mid)
 
-					return response
-				}
+				return response
 			}
+		}
 
-			// clear the current cache
-			dbCache.Delete("elements")
+		// clear the current cache
+		dbCache.Delete("elements")
 
-			// write the data to the database
-			if err := dbUpdate("elements", struct{ Name string }{Name: body.Name}, struct{ Mid string }{Mid: mid}); err != nil {
-				response.Status = fiber.StatusInternalServerError
-				response.Message = "error while writing reservation to database"
+		// write the data to the database
+		if err := store.RenameElement(mid, body.Name); err != nil {
+			response.Status = fiber.StatusInternalServerError
+			response.Message = "error while writing reservation to database"
 
-				logger.Error().Msgf("can't write reservation to database: %v", err)
-			} else {
-				response = getElements(c)
+			logger.Error().Msgf("can't write reservation to database: %v", err)
+		} else {
+			response = getElements(c)
 
-				logger.Debug().Msgf("modified reservation for element %q", mid)
-			}
+			logger.Debug().Msgf("modified reservation for element %q", mid)
 		}
 	}
 
@@ -687,35 +543,25 @@ func patchElements(c *fiber.Ctx) responseMessage {
 func deleteElements(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
-	if user, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
+	mid := c.Query("mid")
 
-		logger.Error().Msgf("can't check user: %v", err)
-	} else if !user {
-		response.Status = fiber.StatusUnauthorized
+	if !isValidMid(mid) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid element name"
 
-		logger.Info().Msg("request is not authorized as user")
+		logger.Info().Msgf("can't delete element: invalid element-name: %q", mid)
 	} else {
-		mid := c.Query("mid")
+		dbCache.Delete("elements")
 
-		if ok, err := isValidMid(mid); !ok || err != nil {
-			response.Status = fiber.StatusBadRequest
-			response.Message = "invalid element name"
+		if err := store.DeleteElements(mid); err != nil {
+			response.Status = fiber.StatusInternalServerError
+			response.Message = "error while deleting reservation from database"
 
-			logger.Info().Msgf("can't delete element: invalid element-name: %q", mid)
+			logger.Error().Msgf("can't delete reservation from database: %v", err)
 		} else {
-			dbCache.Delete("elements")
-
-			if err := dbDelete("elements", struct{ Mid string }{Mid: mid}); err != nil {
-				response.Status = fiber.StatusInternalServerError
-				response.Message = "error while deleting reservation from database"
-
-				logger.Error().Msgf("can't delete reservation from database: %v", err)
-			} else {
-				response = getElements(c)
+			response = getElements(c)
 
-				logger.Debug().Msgf("deleted reservation for %q", mid)
-			}
+			logger.Debug().Msgf("deleted reservation for %q", mid)
 		}
 	}
 
@@ -726,6 +572,17 @@ func deleteElements(c *fiber.Ctx) responseMessage {
 type AddUserBody struct {
 	Name     string
 	Password string
+	Role     string
+	Email    string
+}
+
+// user-data sent to the client
+type UserData struct {
+	Uid   int     `json:"uid"`
+	Name  string  `json:"name"`
+	Role  string  `json:"role"`
+	Totp  bool    `json:"totp"`
+	Email *string `json:"email"`
 }
 
 // user-entry in the database
@@ -734,6 +591,15 @@ type UserDB struct {
 	Name     string `json:"name"`
 	Password []byte `json:"password"`
 	Tid      int    `json:"tid"`
+	Role     string `json:"role"`
+	// address for password-reset-mails
+	Email *string `json:"email"`
+	// secret of the activated totp, nil if disabled
+	Totp *string `json:"-"`
+	// secret of a started totp-enrollment
+	Enrollment *string `json:"-"`
+	// last used totp-time-step
+	Step int64 `json:"-"`
 }
 
 // hashes a password
@@ -745,29 +611,29 @@ func hashPassword(password string) ([]byte, error) {
 func getUsers(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if isAdmin, err := checkAdmin(c); err != nil {
+	// retrieve all users
+	if users, err := store.GetUsers(); err != nil {
 		response.Status = fiber.StatusInternalServerError
+		response.Message = "can't get users from database"
 
-		logger.Error().Msgf("can't check for admin-user: %v", err)
-	} else if !isAdmin {
-		response.Status = fiber.StatusUnauthorized
-
-		logger.Info().Msg("request is not authorized as admin")
+		logger.Error().Msgf("can't get users from database: %v", err)
 	} else {
-		// retrieve all users
-		if users, err := dbSelect[struct {
-			Uid  int    `json:"uid"`
-			Name string `json:"name"`
-		}]("users", ""); err != nil {
-			response.Status = fiber.StatusInternalServerError
-			response.Message = "can't get users from database"
+		// strip the password-hashes and token-ids
+		usersData := make([]UserData, len(users))
+
+		for ii, user := range users {
+			usersData[ii] = UserData{
+				Uid:   user.Uid,
+				Name:  user.Name,
+				Role:  user.Role,
+				Totp:  user.Totp != nil,
+				Email: user.Email,
+			}
+		}
 
-			logger.Error().Msgf("can't get users from database: %v", err)
-		} else {
-			response.Data = users
+		response.Data = usersData
 
-			logger.Debug().Msg("retrieved users from database")
-		}
+		logger.Debug().Msg("retrieved users from database")
 	}
 
 	return response
@@ -776,15 +642,7 @@ func getUsers(c *fiber.Ctx) responseMessage {
 func getReservations(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		logger.Info().Msg("request in not authorized")
-	} else if res, err := dbSelect[ElementDB]("elements", "reservation IS NOT NULL"); err != nil {
+	if res, err := store.GetReservations(); err != nil {
 		response.Status = fiber.StatusInternalServerError
 
 		logger.Error().Msgf("can't get reserved elements from database: %v", err)
@@ -799,23 +657,13 @@ func getReservations(c *fiber.Ctx) responseMessage {
 func getSponsorships(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
+	if res, err := store.GetSponsorships(); err != nil {
 		response.Status = fiber.StatusInternalServerError
 
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		logger.Info().Msg("request in not authorized")
+		logger.Error().Msgf("can't get sponsored elements from database: %v", err)
 	} else {
-		if res, err := dbSelect[ElementDBNoReservation]("elements", "reservation IS NULL"); err != nil {
-			response.Status = fiber.StatusInternalServerError
-
-			logger.Error().Msgf("can't get sponsored elements from database: %v", err)
-		} else {
 
-			response.Data = res
-		}
+		response.Data = res
 	}
 
 	return response
@@ -824,46 +672,50 @@ func getSponsorships(c *fiber.Ctx) responseMessage {
 func getCertificates(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include mid"
 
 		logger.Info().Msg("query doesn't include mid")
 	} else {
 		// get the element from the database
-		if res, err := dbSelect[ElementDB]("elements", "mid = ?", mid); err != nil {
+		if element, err := store.GetElement(mid); err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("can't get element %q from database: %v", mid, err)
-		} else if len(res) != 1 {
+		} else if element == nil {
 			response.Status = fiber.StatusBadRequest
 			response.Message = "query doesn't include valid mid"
 
 			logger.Info().Msgf("query doesn't include valid mid: %q", mid)
+		} else if element.Reservation != nil {
+			// serials are only issued for confirmed sponsorships
+			response.Status = fiber.StatusConflict
+			response.Message = "element isn't a confirmed sponsorship"
+
+			logger.Info().Msgf("can't create certificate for %q: element isn't a confirmed sponsorship", mid)
 		} else {
-			// create the pdf
+			// queue the pdf, the result can be fetched from the job once it is done,
+			// the mid is copied because fiber reuses the memory of the query after the request
 			certData := CertificateData{
 				Reservation: ReservationData{
-					Mid:  mid,
-					Name: res[0].Name,
+					Mid:  strings.Clone(mid),
+					Name: element.Name,
 				},
 			}
 
-			if err := certData.create(); err != nil {
+			if job, err := submitRenderJob(certData); errors.Is(err, errRenderQueueFull) {
+				response.Status = fiber.StatusServiceUnavailable
+				response.Message = "too many certificates are being rendered"
+
+				logger.Warn().Msgf("can't create certificate for %q: %v", mid, err)
+			} else if err != nil {
 				response.Status = fiber.StatusInternalServerError
 
 				logger.Error().Msgf("can't create certificate for %q; %v", mid, err)
 			} else {
-				defer certData.cleanup()
-
-				c.Attachment(certData.PDFFile)
-				c.SendFile(certData.PDFFile)
+				response.Status = fiber.StatusAccepted
+				response.Data = getRenderJob(job.Id)
 			}
 		}
 	}
@@ -881,25 +733,36 @@ func postUsers(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 	body := AddUserBody{}
 
-	if admin, err := checkAdmin(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for admin-user: %v", err)
-	} else if !admin {
-		response.Status = fiber.StatusUnauthorized
-
-		logger.Info().Msg("request is not authorized as user")
-	} else if err := c.BodyParser(&body); err != nil {
+	if err := c.BodyParser(&body); err != nil {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "invalid message-body"
 
-		logger.Warn().Msg(`body can't be parsed as "struct{ name string; Password string }"`)
+		logger.Warn().Msg(`body can't be parsed as "struct{ name string; Password string; Role string; Email string }"`)
+	} else if body.Email != "" && !validateMail(body.Email) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid e-mail-address"
+
+		logger.Info().Msgf("can't add user: invalid e-mail-address %q", body.Email)
 	} else {
-		if dbUsers, err := dbSelect[UserDB]("users", "name = ? LIMIT 1", body.Name); err != nil {
+		// new users get the least privileges, if no role is specified
+		if body.Role == "" {
+			body.Role = roleViewer
+		}
+
+		if !validateRole(body.Role) {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "invalid role"
+
+			logger.Info().Msgf("can't add user: invalid role %q", body.Role)
+
+			return response
+		}
+
+		if dbUser, err := store.GetUserByName(body.Name); err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("can't read users from database: %v", err)
-		} else if len(dbUsers) != 0 {
+		} else if dbUser != nil {
 			response.Status = fiber.StatusBadRequest
 			response.Message = "user already exists"
 
@@ -911,10 +774,7 @@ func postUsers(c *fiber.Ctx) responseMessage {
 
 				logger.Error().Msgf("can't hash password: %v", err)
 			} else {
-				if err := dbInsert("users", struct {
-					Name     string
-					Password []byte
-				}{Name: body.Name, Password: hashedPassword}); err != nil {
+				if err := store.AddUser(body.Name, hashedPassword, body.Role, optionalString(body.Email)); err != nil {
 					response.Status = fiber.StatusInternalServerError
 					response.Message = "can't add user to database"
 
@@ -922,7 +782,7 @@ func postUsers(c *fiber.Ctx) responseMessage {
 				} else {
 					response = getUsers(c)
 
-					logger.Debug().Msgf("added user %q", body.Name)
+					logger.Debug().Msgf("added user %q with role %q", body.Name, body.Role)
 				}
 			}
 		}
@@ -934,62 +794,98 @@ func postUsers(c *fiber.Ctx) responseMessage {
 func postReservations(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		// check if mid is in query
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid mid"
 
 		logger.Info().Msg("query doesn't include valid mid")
-	} else if userData, err := dbSelect[ElementDB]("elements", "mid = ?", mid); err != nil {
+	} else if userData, err := store.GetElement(mid); err != nil {
 		response.Status = fiber.StatusInternalServerError
 
 		logger.Error().Msgf("can't retrieve element-data for %q: %v", mid, err)
-	} else if len(userData) != 1 {
+	} else if userData == nil || userData.Confirmation != nil {
 		response.Status = fiber.StatusNotFound
 		response.Message = "no reservation found"
 
 		logger.Info().Msgf("no element-reservation for %q", mid)
+	} else if due := amountDue(userData.Mid, userData.Due); userData.Received < due && !c.QueryBool("override") {
+		response.Status = fiber.StatusPaymentRequired
+		response.Message = "reservation isn't fully paid"
+
+		logger.Info().Msgf("can't confirm reservation for %q: received %d of %d cents", mid, userData.Received, due)
 	} else {
-		// create the certificate and send it via e-mail
-		certData := CertificateData{
-			Reservation: ReservationData{
-				Mid:  mid,
-				Name: userData[0].Name,
-				Mail: *userData[0].Mail,
-			},
+		if userData.Received < due {
+			logger.Warn().Msgf("user with uid = %d confirmed reservation for %q with %d of %d cents received", getSession(c).Uid, mid, userData.Received, due)
 		}
 
-		defer certData.cleanup()
+		if response = confirmSponsorship(*userData); response.Status < 400 {
+			response = getReservations(c)
+		}
+	}
 
-		if err := certData.create(); err != nil {
-			response.Status = fiber.StatusInternalServerError
-			response.Message = "error while creating certificate"
+	return response
+}
 
-			logger.Error().Msgf("can't create certificate for %q: %v", mid, err)
-		} else if err := certData.send(); err != nil {
-			response.Status = fiber.StatusInternalServerError
-			response.Message = "error while sending certificate"
+// creates the certificate of a reservation, sends it to the sponsor and turns the reservation into a sponsorship
+func confirmSponsorship(element ElementDB) responseMessage {
+	var response responseMessage
 
-			logger.Error().Msgf("can't send certificate for %q: %v", mid, err)
-		} else if err := dbUpdate("elements", struct {
-			Reservation *string
-			Mail        *string
-		}{}, struct{ Mid string }{Mid: mid}); err != nil {
-			response.Status = fiber.StatusInternalServerError
+	if element.Reservation == nil || element.Mail == nil {
+		response.Status = fiber.StatusConflict
+		response.Message = "sponsorship is already confirmed"
 
-			logger.Error().Msgf("can't write reservation-confirm to database for %q: %v", mid, err)
-		} else {
-			dbCache.Delete("elements")
+		logger.Info().Msgf("sponsorship for %q is already confirmed", element.Mid)
+
+		return response
+	}
+
+	// claim the confirmation first, so concurrent requests can't send the certificate twice
+	if ok, err := store.ConfirmElement(element.Mid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't write reservation-confirm to database for %q: %v", element.Mid, err)
+
+		return response
+	} else if !ok {
+		response.Status = fiber.StatusConflict
+		response.Message = "sponsorship is already confirmed"
+
+		logger.Info().Msgf("sponsorship for %q was confirmed by another request", element.Mid)
+
+		return response
+	}
+
+	dbCache.Delete("elements")
+
+	certData := CertificateData{
+		Reservation: ReservationData{
+			Mid:  element.Mid,
+			Name: element.Name,
+			Mail: *element.Mail,
+		},
+	}
+
+	certData, err := renderCertificate(certData)
+
+	if err != nil {
+		response.Status = fiber.StatusInternalServerError
+		response.Message = "error while creating certificate"
+
+		logger.Error().Msgf("can't create certificate for %q: %v", element.Mid, err)
+	} else if err := certData.send(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+		response.Message = "error while sending certificate"
+
+		logger.Error().Msgf("can't queue certificate for %q: %v", element.Mid, err)
+	}
+
+	// keep the reservation, so the confirmation can be repeated
+	if response.Status >= 400 {
+		if err := store.UnconfirmElement(element.Mid, *element.Mail); err != nil {
+			logger.Error().Msgf("can't restore reservation for %q: %v", element.Mid, err)
 		}
 
-		response = getReservations(c)
+		dbCache.Delete("elements")
 	}
 
 	return response
@@ -1006,13 +902,13 @@ func changePassword(uid int, password string) responseMessage {
 		logger.Error().Msgf("can't hash password: %v", err)
 	} else {
 		// increase the token-id of the user to make the current-token invalid
-		if err := incTokenId(uid); err != nil {
+		if err := store.IncTokenId(uid); err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("can't increase the tid: %v", err)
 		} else {
 			// update the databse with the new password
-			if err := dbUpdate("users", struct{ Password []byte }{Password: hashedPassword}, struct{ Uid int }{Uid: uid}); err != nil {
+			if err := store.SetPassword(uid, hashedPassword); err != nil {
 				response.Status = fiber.StatusInternalServerError
 				response.Message = "can't update password"
 
@@ -1032,51 +928,112 @@ func changePassword(uid int, password string) responseMessage {
 func patchUsers(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
-	if admin, err := checkAdmin(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-		response.Message = "error while checking the authorization"
+	body := struct {
+		Password  string  `json:"password"`
+		Role      string  `json:"role"`
+		ResetTotp bool    `json:"reset_totp"`
+		Email     *string `json:"email"`
+	}{}
 
-		logger.Error().Msgf("can't check for admin: %v", err)
-	} else if !admin {
-		response.Status = fiber.StatusUnauthorized
+	// check wether a valid uid is present
+	if uid := c.QueryInt("uid", -1); uid < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid uid"
 
-		logger.Info().Msg("user is no admin")
+		logger.Info().Msg("query doesn't include valid uid")
 	} else {
-		body := struct {
-			Password string `json:"password"`
-		}{}
+		// try to parse the body
+		if err := c.BodyParser(&body); err != nil {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "invalid message-body"
+
+			logger.Warn().Msg(`body can't be parsed as "struct{ password string; role string }"`)
+		} else if body.Password != "" && !validatePassword(body.Password) {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "invalid password"
 
-		// check wether a valid uid is present
-		if uid := c.QueryInt("uid", -1); uid < 0 {
+			logger.Info().Msg("invalid password")
+		} else if body.Email != nil && *body.Email != "" && !validateMail(*body.Email) {
 			response.Status = fiber.StatusBadRequest
-			response.Message = "query doesn't include valid uid"
+			response.Message = "invalid e-mail-address"
 
-			logger.Info().Msg("query doesn't include valid uid")
+			logger.Info().Msgf("can't modify user: invalid e-mail-address %q", *body.Email)
+		} else if body.Role != "" && !validateRole(body.Role) {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "invalid role"
+
+			logger.Info().Msgf("can't modify user: invalid role %q", body.Role)
+		} else if body.Role != "" && body.Role != roleAdmin && uid == getSession(c).Uid {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "can't remove own admin-role"
+
+			logger.Info().Msgf("user with uid = %d tried to remove its own admin-role", uid)
 		} else {
-			// try to parse the body
-			if err := c.BodyParser(&body); err != nil {
+			// check, wether the user exists
+			if dbUser, err := store.GetUser(uid); err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't read users from database: %v", err)
+			} else if dbUser == nil {
 				response.Status = fiber.StatusBadRequest
-				response.Message = "invalid message-body"
+				response.Message = "user doesn't exist"
 
-				logger.Warn().Msg(`body can't be parsed as "struct{ password string }"`)
+				logger.Info().Msgf("can't modify user: user with uid %q doesn't exist", uid)
 			} else {
-				// check, wether the user exists
-				if dbUsers, err := dbSelect[UserDB]("users", "uid = ? LIMIT 1", uid); err != nil {
-					response.Status = fiber.StatusInternalServerError
+				// everything is valid
+
+				// change the role, if requested
+				if body.Role != "" && body.Role != dbUser.Role {
+					if err := store.SetRole(uid, body.Role); err != nil {
+						response.Status = fiber.StatusInternalServerError
 
-					logger.Error().Msgf("can't read users from database: %v", err)
-				} else if len(dbUsers) != 1 {
-					response.Status = fiber.StatusBadRequest
-					response.Message = "user doesn't exist"
+						logger.Error().Msgf("can't set role for user with uid = %d: %v", uid, err)
 
-					logger.Info().Msgf("can't modify user: user with uid %q doesn't exist", uid)
-				} else {
-					// everything is valid
+						return response
+					} else if err := store.IncTokenId(uid); err != nil {
+						// invalidate the sessions, so the new role takes effect
+						response.Status = fiber.StatusInternalServerError
+
+						logger.Error().Msgf("can't increase tid for user with uid = %d: %v", uid, err)
 
-					if response = changePassword(uid, body.Password); response.Status == fiber.StatusOK {
-						response = getUsers(c)
+						return response
 					}
+
+					logger.Info().Msgf("changed role of user with uid = %d to %q", uid, body.Role)
 				}
+
+				// change the e-mail-address, if requested
+				if body.Email != nil {
+					if err := store.SetEmail(uid, optionalString(*body.Email)); err != nil {
+						response.Status = fiber.StatusInternalServerError
+
+						logger.Error().Msgf("can't set e-mail-address for user with uid = %d: %v", uid, err)
+
+						return response
+					}
+				}
+
+				// remove the second factor, if the user lost it
+				if body.ResetTotp {
+					if err := store.DisableTotp(uid); err != nil {
+						response.Status = fiber.StatusInternalServerError
+
+						logger.Error().Msgf("can't reset totp for user with uid = %d: %v", uid, err)
+
+						return response
+					}
+
+					logger.Info().Msgf("reset totp of user with uid = %d", uid)
+				}
+
+				// change the password, if requested
+				if body.Password != "" {
+					if response = changePassword(uid, body.Password); response.Status != fiber.StatusOK {
+						return response
+					}
+				}
+
+				response = getUsers(c)
 			}
 		}
 	}
@@ -1088,22 +1045,19 @@ func patchUsers(c *fiber.Ctx) responseMessage {
 func deleteUsers(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
-	if admin, err := checkAdmin(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for admin-user: %v", err)
-	} else if !admin {
-		response.Status = fiber.StatusUnauthorized
-
-		// check wether there is a valid uid
-	} else if uid := c.QueryInt("uid", -1); uid < 0 {
+	if uid := c.QueryInt("uid", -1); uid < 0 {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid uid"
 
 		logger.Info().Msg("query doesn't include valid uid")
+	} else if uid == getSession(c).Uid {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't delete own user"
+
+		logger.Info().Msgf("user with uid = %d tried to delete itself", uid)
 	} else {
 		// delete the user from the database
-		if err := dbDelete("users", struct{ Uid int }{Uid: uid}); err != nil {
+		if err := store.DeleteUser(uid); err != nil {
 			response.Status = fiber.StatusInternalServerError
 			response.Message = "can't delete user"
 
@@ -1121,21 +1075,13 @@ func deleteUsers(c *fiber.Ctx) responseMessage {
 func deleteReservations(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		// check for mid in query
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid mid"
 
 		logger.Info().Msg("query doesn't include valid mid")
 	} else {
-		if err := dbDelete("elements", struct{ Mid string }{Mid: mid}); err != nil {
+		if err := store.DeleteElements(mid); err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("error while removing reservation for element %q from database: %v", mid, err)
@@ -1152,21 +1098,13 @@ func deleteReservations(c *fiber.Ctx) responseMessage {
 func deleteSponsorships(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		// check for mid in query
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid mid"
 
 		logger.Info().Msg("query doesn't include valid mid")
 	} else {
-		if err := dbDelete("elements", struct{ Mid string }{Mid: mid}); err != nil {
+		if err := store.DeleteElements(mid); err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("error while removing sponsorship for element %q from database: %v", mid, err)
@@ -1180,43 +1118,61 @@ func deleteSponsorships(c *fiber.Ctx) responseMessage {
 	return response
 }
 
-// handles patch-requests to change the users password
-func patchUserPassword(c *fiber.Ctx) responseMessage {
+// handles patch-requests to change the users e-mail-address
+func patchUserEmail(c *fiber.Ctx) responseMessage {
 	response := responseMessage{}
 
-	if user, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
+	// parse the body
+	var body struct {
+		Email string `json:"email"`
+	}
+
+	uid := getSession(c).Uid
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ email string }"`)
+	} else if body.Email != "" && !validateMail(body.Email) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid e-mail-address"
 
-		logger.Error().Msgf("can't check for user: %v", err)
-	} else if !user {
-		response.Status = fiber.StatusUnauthorized
+		logger.Info().Msgf("invalid e-mail-address %q", body.Email)
+	} else if err := store.SetEmail(uid, optionalString(body.Email)); err != nil {
+		response.Status = fiber.StatusInternalServerError
 
-		logger.Info().Msg("request is not authorized as user")
+		logger.Error().Msgf("can't set e-mail-address for user with uid = %d: %v", uid, err)
 	} else {
-		// parse the body
-		var body struct {
-			Password string `json:"password"`
-		}
+		logger.Debug().Msgf("updated e-mail-address for user with uid = %d", uid)
 
-		if uid, _, err := extractJWT(c); err != nil {
-			response.Status = fiber.StatusBadRequest
-			response.Message = "query doesn't include valid uid"
+		response.Status = fiber.StatusOK
+	}
 
-			logger.Warn().Msg("can't extract uid from query")
-		} else if err := c.BodyParser(&body); err != nil {
-			response.Status = fiber.StatusBadRequest
+	return response
+}
 
-			logger.Warn().Msg(`body can't be parsed as "struct{ password string }"`)
-		} else if !validatePassword(body.Password) {
-			response.Status = fiber.StatusBadRequest
-			response.Message = "invalid password"
+// handles patch-requests to change the users password
+func patchUserPassword(c *fiber.Ctx) responseMessage {
+	response := responseMessage{}
 
-			logger.Info().Msg("invalid password")
-		} else {
-			// everything is valid
+	// parse the body
+	var body struct {
+		Password string `json:"password"`
+	}
 
-			return changePassword(uid, body.Password)
-		}
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ password string }"`)
+	} else if !validatePassword(body.Password) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid password"
+
+		logger.Info().Msg("invalid password")
+	} else {
+		// everything is valid
+
+		return changePassword(getSession(c).Uid, body.Password)
 	}
 
 	return response
@@ -1225,15 +1181,7 @@ func patchUserPassword(c *fiber.Ctx) responseMessage {
 func patchReservations(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		// check for mid in query
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid mid"
 
@@ -1248,7 +1196,7 @@ func patchReservations(c *fiber.Ctx) responseMessage {
 			logger.Warn().Msg(`body can't be parsed as "struct{ name string }"`)
 		} else {
 			// update the database with the new name
-			dbUpdate("elements", body, struct{ Mid string }{Mid: mid})
+			store.RenameElement(mid, body.Name)
 
 			dbCache.Delete("elements")
 
@@ -1262,30 +1210,36 @@ func patchReservations(c *fiber.Ctx) responseMessage {
 func patchSponsorships(c *fiber.Ctx) responseMessage {
 	var response responseMessage
 
-	if ok, err := checkUser(c); err != nil {
-		response.Status = fiber.StatusInternalServerError
-
-		logger.Error().Msgf("can't check user: %v", err)
-	} else if !ok {
-		response.Status = fiber.StatusUnauthorized
-
-		// check for mid in query
-	} else if mid := c.Query("mid"); mid == "" {
+	if mid := c.Query("mid"); mid == "" {
 		response.Status = fiber.StatusBadRequest
 		response.Message = "query doesn't include valid mid"
 
 		logger.Info().Msg("query doesn't include valid mid")
 	} else {
-		// parse the body
-		body := struct{ Name string }{}
+		// parse the body, the address is only changed if it is included
+		body := struct {
+			Name    string
+			Address *string
+		}{}
 
 		if err := c.BodyParser(&body); err != nil {
 			response.Status = fiber.StatusBadRequest
 
-			logger.Warn().Msg(`body can't be parsed as "struct{ name string }"`)
+			logger.Warn().Msg(`body can't be parsed as "struct{ name string; address *string }"`)
+		} else if body.Address != nil && len(*body.Address) > addressLength {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "address is too long"
+
+			logger.Info().Msgf("can't change address of %q: address is too long", mid)
 		} else {
 			// update the database with the new name
-			dbUpdate("elements", body, struct{ Mid string }{Mid: mid})
+			store.RenameElement(mid, body.Name)
+
+			if body.Address != nil {
+				if err := store.SetAddress(mid, optionalString(strings.TrimSpace(*body.Address))); err != nil {
+					logger.Error().Msgf("can't change address of %q: %v", mid, err)
+				}
+			}
 
 			dbCache.Delete("elements")
 
@@ -1305,40 +1259,36 @@ func handleWelcome(c *fiber.Ctx) error {
 		LoggedIn: false,
 	}
 
-	if ok, err := checkUser(c); err != nil {
+	if session, err := checkUser(c); err != nil {
 		response.Status = fiber.StatusInternalServerError
 
 		logger.Warn().Msgf("can't check user: %v", err)
-	} else if !ok {
+	} else if session == nil {
 		response.Status = fiber.StatusNoContent
 	} else {
-		if uid, _, err := extractJWT(c); err != nil {
-			response.Status = fiber.StatusBadRequest
+		if user, err := store.GetUser(session.Uid); err != nil {
+			response.Status = fiber.StatusInternalServerError
 
-			logger.Error().Msgf("can't extract JWT: %v", err)
+			logger.Error().Msgf("can't get users from database: %v", err)
 		} else {
-			if users, err := dbSelect[UserDB]("users", "uid = ? LIMIT 1", strconv.Itoa(uid)); err != nil {
-				response.Status = fiber.StatusInternalServerError
+			if user == nil {
+				response.Status = fiber.StatusForbidden
+				response.Message = "unknown user"
 
-				logger.Error().Msgf("can't get users from database: %v", err)
+				removeSessionCookie(c)
 			} else {
-				if len(users) != 1 {
-					response.Status = fiber.StatusForbidden
-					response.Message = "unknown user"
-
-					removeSessionCookie(c)
-				} else {
-					user := users[0]
-
-					response.Data = UserLogin{
-						Uid:      user.Uid,
-						Name:     user.Name,
-						LoggedIn: true,
-					}
+				response.Data = UserLogin{
+					Uid:      user.Uid,
+					Name:     user.Name,
+					Role:     user.Role,
+					LoggedIn: true,
+					Email:    user.Email,
+
+					TotpRequired: totpMissing(user),
 				}
-
-				logger.Debug().Msgf("welcomed user with uid = %v", uid)
 			}
+
+			logger.Debug().Msgf("welcomed user with uid = %v", session.Uid)
 		}
 	}
 
@@ -1355,25 +1305,14 @@ type LoginBody struct {
 type UserLogin struct {
 	Uid      int    `json:"uid"`
 	Name     string `json:"name"`
+	Role     string `json:"role"`
 	LoggedIn bool   `json:"logged_in"`
-}
-
-// retrieves the current tid for a specific user from the database
-func getTokenId(uid int) (int, error) {
-	if response, err := dbSelect[UserDB]("users", "uid = ? LIMIT 1", uid); err != nil {
-		return -1, err
-	} else if len(response) != 1 {
-		return -1, fmt.Errorf("can't get user with uid = %q from database", uid)
-	} else {
-		return response[0].Tid, nil
-	}
-}
-
-// increases the tid of a user
-func incTokenId(uid int) error {
-	_, err := db.Exec("UPDATE users SET tid = tid + 1 WHERE uid = ?", uid)
-
-	return err
+	// address for password-reset-mails
+	Email *string `json:"email,omitempty"`
+	// the login waits for the second factor
+	Totp bool `json:"totp,omitempty"`
+	// the user has to set up the second factor before accessing anything else
+	TotpRequired bool `json:"totp_required,omitempty"`
 }
 
 var messageWrongLogin = "Unkown user or wrong password"
@@ -1393,63 +1332,114 @@ func handleLogin(c *fiber.Ctx) error {
 		logger.Warn().Msgf("can't parse login-body: %v", err)
 	} else {
 		// try to get the hashed password from the database
-		dbResult, err := dbSelect[UserDB]("users", "name = ? LIMIT 1", body.User)
+		user, err := store.GetUserByName(body.User)
 
 		if err != nil {
 			response.Status = fiber.StatusInternalServerError
 
 			logger.Error().Msgf("can't get users from the database: %v", err)
-		} else if len(dbResult) != 1 {
+		} else if response = checkLoginThrottle(c, user); response.Status >= 400 {
+			// too many failed logins, the client has to wait
+		} else if user == nil {
 			response.Status = fiber.StatusForbidden
 			response.Message = messageWrongLogin
 
 			logger.Info().Msgf("user with name = %q doesn't exist", body.User)
+
+			recordLoginFailure(c, body.User, nil)
 		} else {
 			response.Data = UserLogin{
 				LoggedIn: false,
 			}
 
-			user := dbResult[0]
-
-			if len(dbResult) != 1 || bcrypt.CompareHashAndPassword(user.Password, []byte(body.Password)) != nil {
+			if bcrypt.CompareHashAndPassword(user.Password, []byte(body.Password)) != nil {
 				response.Status = fiber.StatusUnauthorized
 				response.Message = messageWrongLogin
 
 				logger.Debug().Msgf("can't login: wrong username or password")
-			} else {
-				// get the token-id
-				if tid, err := getTokenId(user.Uid); err != nil {
-					response.Status = fiber.StatusInternalServerError
 
-					logger.Error().Msgf("can't get tid for user with uid = %q", user.Uid)
+				recordLoginFailure(c, body.User, user)
+			} else {
+				if user.Totp != nil {
+					// ask for the second factor before issuing the session
+					response = startLoginChallenge(c, user)
 				} else {
-					// create the jwt
-					jwt, err := config.signJWT(JWTPayload{
-						Uid: user.Uid,
-						Tid: tid,
-					})
+					clearLoginFailures(user)
 
-					if err != nil {
-						response.Status = fiber.StatusInternalServerError
+					response = startSession(c, user)
+				}
+			}
+		}
+	}
+
+	return response.send(c)
+}
 
-						logger.Error().Msgf("json-webtoken creation failed: %v", err)
-					} else {
-						setSessionCookie(c, &jwt)
+// issues a session-JWT for a user and stores it in the session-cookie
+func startSession(c *fiber.Ctx, user *UserDB) responseMessage {
+	response := responseMessage{
+		Data: UserLogin{
+			LoggedIn: false,
+		},
+	}
 
-						response.Data = UserLogin{
-							Uid:      user.Uid,
-							Name:     user.Name,
-							LoggedIn: true,
-						}
+	now := time.Now()
 
-						logger.Info().Msgf("user with uid = %q logged in", user.Uid)
-					}
-				}
+	// get the token-id
+	if tid, err := store.GetTokenId(user.Uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get tid for user with uid = %q", user.Uid)
+	} else if jti, err := randomToken(16); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create session-id: %v", err)
+	} else if err := store.AddSession(SessionDB{
+		Jti:     jti,
+		Uid:     user.Uid,
+		Created: now.Unix(),
+		Used:    now.Unix(),
+		Ip:      c.IP(),
+		Agent:   truncateString(c.Get(fiber.HeaderUserAgent), 255),
+	}); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't store session for user with uid = %d: %v", user.Uid, err)
+	} else {
+		// create the jwt
+		jwt, err := config.signJWT(jti, JWTPayload{
+			Uid:  user.Uid,
+			Tid:  tid,
+			Role: user.Role,
+		})
+
+		if err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("json-webtoken creation failed: %v", err)
+		} else {
+			setSessionCookie(c, &jwt)
+
+			response.Data = UserLogin{
+				Uid:      user.Uid,
+				Name:     user.Name,
+				Role:     user.Role,
+				LoggedIn: true,
+				Email:    user.Email,
+
+				TotpRequired: totpMissing(user),
 			}
+
+			logger.Info().Msgf("user with uid = %q logged in", user.Uid)
+		}
+
+		// remove expired sessions
+		if err := store.PurgeSessions(now.Add(-config.SessionExpire)); err != nil {
+			logger.Error().Msgf("can't remove expired sessions: %v", err)
 		}
 	}
 
-	return response.send(c)
+	return response
 }
 
 // removes the session-coockie from a request
@@ -1467,6 +1457,13 @@ func removeSessionCookie(c *fiber.Ctx) {
 func handleLogout(c *fiber.Ctx) error {
 	logger.Debug().Msgf("HTTP %s request: %q", c.Method(), c.OriginalURL())
 
+	// remove the session from the database
+	if session, err := extractJWT(c); err == nil {
+		if _, err := store.DeleteSession(session.Jti, session.Uid); err != nil {
+			logger.Error().Msgf("can't remove session of user with uid = %d: %v", session.Uid, err)
+		}
+	}
+
 	removeSessionCookie(c)
 
 	return responseMessage{
@@ -1477,21 +1474,45 @@ func handleLogout(c *fiber.Ctx) error {
 }
 
 func main() {
-	// setup the database-connection
-	sqlConfig := mysql.Config{
-		AllowNativePasswords: true,
-		Net:                  "tcp",
-		User:                 config.Database.User,
-		Passwd:               config.Database.Password,
-		Addr:                 config.Database.Host,
-		DBName:               config.Database.Database,
+	initConfig()
+
+	// setup the mail-transport
+	if m, err := newMailer(); err != nil {
+		logger.Fatal().Msgf("can't setup mail-transport: %v", err)
+	} else {
+		mailer = m
 	}
 
 	// connect to the database
-	db, _ = sql.Open("mysql", sqlConfig.FormatDSN())
-	db.SetMaxIdleConns(10)
-	db.SetMaxIdleConns(100)
-	db.SetConnMaxLifetime(time.Minute)
+	if s, err := openStore(); err != nil {
+		logger.Fatal().Msgf("can't open database: %v", err)
+	} else {
+		store = s
+
+		defer store.Close()
+	}
+
+	// refuse to start, if the database-schema isn't up to date
+	if version, err := store.SchemaVersion(); err != nil {
+		logger.Fatal().Msgf(`can't read schema-version, run "setup migrate": %v`, err)
+	} else if latest, err := latestSchemaVersion(config.Database.Driver); err != nil {
+		logger.Fatal().Msgf("can't read schema-migrations: %v", err)
+	} else if version < latest {
+		logger.Fatal().Msgf(`database-schema is at version %d but version %d is required, run "setup migrate"`, version, latest)
+	}
+
+	// setup the certificate-renderer
+	if r, err := newRenderer(); err != nil {
+		logger.Fatal().Msgf("can't setup certificate-renderer: %v", err)
+	} else {
+		renderer = r
+	}
+
+	// render the certificates in the background with a limited number of workers
+	startRenderWorkers(config.Certificate.Workers)
+
+	// deliver the queued mails in the background
+	go runOutbox()
 
 	// setup the cache
 	dbCache = cache.New(config.Cache.Expiration, config.Cache.Purge)
@@ -1500,6 +1521,7 @@ func main() {
 	app := fiber.New(fiber.Config{
 		AppName:               "johannes-pv",
 		DisableStartupMessage: true,
+		ProxyHeader:           config.Server.ProxyHeader,
 	})
 
 	// map with the individual methods
@@ -1510,47 +1532,97 @@ func main() {
 		"DELETE": app.Delete,
 	}
 
-	// map with the individual registered endpoints
-	endpoints := map[string]map[string]func(*fiber.Ctx) responseMessage{
+	// map with the individual registered endpoints and the permission required to access them
+	endpoints := map[string]map[string]Endpoint{
 		"GET": {
-			"elements":     getElements,
-			"users":        getUsers,
-			"reservations": getReservations,
-			"sponsorships": getSponsorships,
-			"certificates": getCertificates,
+			"elements":                      {getElements, permissionPublic},
+			"elements/confirm":              {getElementsConfirm, permissionPublic},
+			"elements/catalogue":            {getElementsCatalogue, permissionPublic},
+			"layout":                        {getLayout, permissionPublic},
+			"users":                         {getUsers, permissionManageUsers},
+			"reservations":                  {getReservations, permissionReadElements},
+			"sponsorships":                  {getSponsorships, permissionReadElements},
+			"certificates":                  {getCertificates, permissionReadElements},
+			"certificates/verify/:serial":   {getCertificatesVerify, permissionPublic},
+			"certificates/export":           {getCertificatesExport, permissionExportCertificates},
+			"certificate-templates":         {getCertificateTemplates, permissionManageTemplates},
+			"certificate-templates/content": {getCertificateTemplatesContent, permissionManageTemplates},
+			"certificate-templates/preview": {getCertificateTemplatesPreview, permissionManageTemplates},
+			"jobs/:id":                      {getJobs, permissionReadElements},
+			"jobs/:id/result":               {getJobsResult, permissionReadElements},
+			"outbox":                        {getOutbox, permissionManageOutbox},
+			"transactions":                  {getTransactions, permissionManagePayments},
+			"receipts":                      {getReceipts, permissionManagePayments},
+			"receipts/pdf":                  {getReceiptsPDF, permissionManagePayments},
+			"lockouts":                      {getLockouts, permissionManageUsers},
+			"sessions":                      {getSessions, permissionManageUsers},
+			"user/sessions":                 {getUserSessions, permissionAccount},
+			"user/totp":                     {getUserTotp, permissionAccount},
+			"tokens":                        {getTokens, permissionAccount},
 		},
 		"POST": {
-			"elements":     postElements,
-			"users":        postUsers,
-			"reservations": postReservations,
+			"elements":              {postElements, permissionPublic},
+			"users":                 {postUsers, permissionManageUsers},
+			"reservations":          {postReservations, permissionConfirmReservations},
+			"outbox":                {postOutbox, permissionManageOutbox},
+			"user/totp":             {postUserTotp, permissionAccount},
+			"user/recovery":         {postUserTotpRecovery, permissionAccount},
+			"tokens":                {postTokens, permissionAccount},
+			"payments":              {postPayments, permissionManagePayments},
+			"transactions":          {postTransactions, permissionManagePayments},
+			"receipts":              {postReceipts, permissionManagePayments},
+			"certificate-templates": {postCertificateTemplates, permissionManageTemplates},
 		},
 		"PATCH": {
-			"elements":      patchElements,
-			"users":         patchUsers,
-			"user/password": patchUserPassword,
-			"reservations":  patchReservations,
-			"sponsorships":  patchSponsorships,
+			"elements":                       {patchElements, permissionEditElements},
+			"users":                          {patchUsers, permissionManageUsers},
+			"user/password":                  {patchUserPassword, permissionAccount},
+			"user/email":                     {patchUserEmail, permissionAccount},
+			"user/totp":                      {patchUserTotp, permissionAccount},
+			"reservations":                   {patchReservations, permissionEditElements},
+			"sponsorships":                   {patchSponsorships, permissionEditElements},
+			"certificates":                   {patchCertificates, permissionDeleteSponsorships},
+			"certificate-templates":          {patchCertificateTemplates, permissionManageTemplates},
+			"certificate-templates/rollback": {patchCertificateTemplatesRollback, permissionManageTemplates},
+			"payments":                       {patchPayments, permissionManagePayments},
+			"transactions":                   {patchTransactions, permissionManagePayments},
 		},
 		"DELETE": {
-			"elements":     deleteElements,
-			"users":        deleteUsers,
-			"reservations": deleteReservations,
-			"sponsorships": deleteSponsorships,
+			"elements":      {deleteElements, permissionConfirmReservations},
+			"users":         {deleteUsers, permissionManageUsers},
+			"reservations":  {deleteReservations, permissionConfirmReservations},
+			"sponsorships":  {deleteSponsorships, permissionDeleteSponsorships},
+			"certificates":  {deleteCertificates, permissionDeleteSponsorships},
+			"outbox":        {deleteOutbox, permissionManageOutbox},
+			"lockouts":      {deleteLockouts, permissionManageUsers},
+			"sessions":      {deleteSessions, permissionManageUsers},
+			"user/sessions": {deleteUserSessions, permissionAccount},
+			"user/totp":     {deleteUserTotp, permissionAccount},
+			"tokens":        {deleteTokens, permissionAccount},
+			"transactions":  {deleteTransactions, permissionManagePayments},
 		},
 	}
 
 	// handle specific requests special
 	app.Get("/api/welcome", handleWelcome)
 	app.Post("/api/login", handleLogin)
+	app.Post("/api/login/totp", handleLoginTotp)
+	app.Post("/api/login/forgot", handleForgotPassword)
+	app.Post("/api/login/reset", handleResetPassword)
 	app.Get("/api/logout", handleLogout)
 
 	// register the registered endpoints
 	for method, handlers := range endpoints {
-		for address, handler := range handlers {
+		for address, endpoint := range handlers {
 			handleMethods[method]("/api/"+address, func(c *fiber.Ctx) error {
 				logger.Debug().Msgf("HTTP %s request: %q", c.Method(), c.OriginalURL())
 
-				return handler(c).send(c)
+				// check wether the user is allowed to access the endpoint
+				if response := authorize(c, endpoint.permission); response.Status >= 400 {
+					return response.send(c)
+				}
+
+				return endpoint.handler(c).send(c)
 			})
 		}
 	}
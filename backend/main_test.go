@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// opens an empty sqlite-database as store
+func setupTestStore(t *testing.T) {
+	s, err := openSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+
+	store = s
+
+	t.Cleanup(func() { store.Close() })
+
+	// apply the schema-migrations
+	migrations, err := fs.Glob(migrationFiles, "migrations/sqlite/*.up.sql")
+
+	if err != nil {
+		t.Fatalf("can't read schema-migrations: %v", err)
+	}
+
+	for _, migration := range migrations {
+		if schema, err := fs.ReadFile(migrationFiles, migration); err != nil {
+			t.Fatalf("can't read schema-migration: %v", err)
+		} else if _, err := s.(*sqlStore).db.Exec(string(schema)); err != nil {
+			t.Fatalf("can't apply schema-migration %q: %v", migration, err)
+		}
+	}
+}
+
+func TestClaimElementConcurrent(t *testing.T) {
+	setupTestStore(t)
+
+	const requests = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+
+	for ii := 0; ii < requests; ii++ {
+		wg.Add(1)
+
+		go func(ii int) {
+			defer wg.Done()
+
+			mail := fmt.Sprintf("sponsor%d@example.org", ii)
+
+			errs <- store.ClaimElement(ElementDBNoReservation{Mid: "pv-a1", Name: fmt.Sprintf("Sponsor %d", ii), Mail: &mail}, fmt.Sprintf("%032d", ii), time.Hour, time.Hour)
+		}(ii)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	won := 0
+
+	for err := range errs {
+		if err == nil {
+			won++
+		} else if !errors.Is(err, errElementUnavailable) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if won != 1 {
+		t.Errorf("%d requests claimed the element, expected exactly 1", won)
+	}
+}
+
+func TestConfirmElementConcurrent(t *testing.T) {
+	setupTestStore(t)
+
+	mail := "erika@example.org"
+	confirmation := strings.Repeat("0", 32)
+
+	if err := store.ClaimElement(ElementDBNoReservation{Mid: "pv-a1", Name: "Erika Mustermann", Mail: &mail}, confirmation, time.Hour, time.Hour); err != nil {
+		t.Fatalf("can't claim element: %v", err)
+	}
+
+	// the reservation can't become a sponsorship before the sponsor confirmed the e-mail-address
+	if ok, err := store.ConfirmElement("pv-a1"); err != nil || ok {
+		t.Errorf("unconfirmed reservation was turned into a sponsorship: %v, %v", ok, err)
+	} else if ok, err := store.ConfirmReservation("pv-a1", confirmation); err != nil || !ok {
+		t.Fatalf("can't confirm reservation: %v", err)
+	}
+
+	const requests = 20
+
+	var wg sync.WaitGroup
+	confirmed := make(chan bool, requests)
+
+	for range requests {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if ok, err := store.ConfirmElement("pv-a1"); err != nil {
+				t.Errorf("can't confirm element: %v", err)
+			} else {
+				confirmed <- ok
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(confirmed)
+
+	won := 0
+
+	for ok := range confirmed {
+		if ok {
+			won++
+		}
+	}
+
+	if won != 1 {
+		t.Errorf("%d requests confirmed the sponsorship, expected exactly 1", won)
+	}
+
+	// a stale copy of the reservation doesn't confirm the sponsorship again
+	if response := confirmSponsorship(ElementDB{Mid: "pv-a1", Name: "Erika Mustermann"}); response.Status != fiber.StatusConflict {
+		t.Errorf("confirmation of a sponsorship without e-mail-address returned status %d, expected %d", response.Status, fiber.StatusConflict)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+// schema-migrations for the individual database-drivers, they are applied by "setup migrate"
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// regex to match the file-names of the migrations
+var migrationRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// returns the version of the newest migration for a database-driver
+func latestSchemaVersion(driver string) (int, error) {
+	entries, err := fs.ReadDir(migrationFiles, path.Join("migrations", driver))
+
+	if err != nil {
+		return -1, err
+	}
+
+	version := 0
+
+	for _, entry := range entries {
+		if results := migrationRegex.FindStringSubmatch(entry.Name()); results != nil && results[3] == "up" {
+			if v, err := strconv.Atoi(results[1]); err != nil {
+				return -1, err
+			} else if v > version {
+				version = v
+			}
+		}
+	}
+
+	return version, nil
+}
@@ -0,0 +1 @@
+CREATE TABLE outbox (id INT NOT NULL KEY auto_increment, sender TINYTEXT NOT NULL, recipients TEXT NOT NULL, subject TEXT NOT NULL, message MEDIUMBLOB NOT NULL, status VARCHAR(8) NOT NULL DEFAULT "queued", attempts INT NOT NULL DEFAULT 0, due BIGINT NOT NULL, reason TEXT NOT NULL DEFAULT "", created BIGINT NOT NULL);
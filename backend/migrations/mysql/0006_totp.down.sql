@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE users DROP COLUMN step, DROP COLUMN enrollment, DROP COLUMN totp;
@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS recovery_codes (uid INT NOT NULL, code CHAR(64) NOT NULL, PRIMARY KEY (uid, code), FOREIGN KEY (uid) REFERENCES users (uid) ON DELETE CASCADE);
+ALTER TABLE users ADD COLUMN totp VARCHAR(32) NULL, ADD COLUMN enrollment VARCHAR(32) NULL, ADD COLUMN step BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+CREATE TABLE login_throttle (kind VARCHAR(8) NOT NULL, subject VARCHAR(64) NOT NULL, failures INT NOT NULL DEFAULT 0, last BIGINT NOT NULL, locked BIGINT NOT NULL DEFAULT 0, PRIMARY KEY (kind, subject));
@@ -0,0 +1 @@
+CREATE TABLE sessions (jti CHAR(32) NOT NULL KEY, uid INT NOT NULL, created BIGINT NOT NULL, used BIGINT NOT NULL, ip VARCHAR(64) NOT NULL DEFAULT "", agent VARCHAR(255) NOT NULL DEFAULT "", FOREIGN KEY (uid) REFERENCES users (uid) ON DELETE CASCADE);
@@ -0,0 +1 @@
+CREATE TABLE api_tokens (id CHAR(16) NOT NULL KEY, uid INT NOT NULL, name VARCHAR(64) NOT NULL, hash CHAR(64) NOT NULL UNIQUE, scopes VARCHAR(255) NOT NULL, created BIGINT NOT NULL, expires BIGINT NULL, used BIGINT NOT NULL DEFAULT 0, ip VARCHAR(64) NOT NULL DEFAULT "", FOREIGN KEY (uid) REFERENCES users (uid) ON DELETE CASCADE);
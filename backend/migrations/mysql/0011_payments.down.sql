@@ -0,0 +1 @@
+ALTER TABLE elements DROP COLUMN reference, DROP COLUMN method, DROP COLUMN paid, DROP COLUMN received, DROP COLUMN due;
@@ -0,0 +1 @@
+ALTER TABLE elements ADD COLUMN due BIGINT NULL, ADD COLUMN received BIGINT NOT NULL DEFAULT 0, ADD COLUMN paid VARCHAR(10) NULL, ADD COLUMN method VARCHAR(16) NULL, ADD COLUMN reference VARCHAR(140) NULL;
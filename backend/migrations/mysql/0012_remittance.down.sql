@@ -0,0 +1 @@
+ALTER TABLE elements DROP COLUMN remittance;
@@ -0,0 +1 @@
+ALTER TABLE elements ADD COLUMN remittance VARCHAR(25) NULL, ADD UNIQUE INDEX elements_remittance (remittance);
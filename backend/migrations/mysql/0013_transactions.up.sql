@@ -0,0 +1 @@
+CREATE TABLE transactions (id INT NOT NULL KEY auto_increment, hash CHAR(64) NOT NULL UNIQUE, booked VARCHAR(10) NOT NULL, amount BIGINT NOT NULL, name VARCHAR(255) NOT NULL DEFAULT "", iban VARCHAR(34) NOT NULL DEFAULT "", purpose TEXT NOT NULL DEFAULT "", mid VARCHAR(16) NULL, reasons VARCHAR(64) NOT NULL DEFAULT "", status VARCHAR(16) NOT NULL DEFAULT "open", created BIGINT NOT NULL);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS receipts;
+ALTER TABLE elements DROP COLUMN address;
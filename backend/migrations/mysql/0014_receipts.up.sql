@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS receipts (id INT NOT NULL KEY auto_increment, year INT NOT NULL, seq INT NOT NULL, number VARCHAR(16) NOT NULL UNIQUE, kind VARCHAR(16) NOT NULL, mids TEXT NOT NULL, name TEXT NOT NULL, mail TEXT NULL, address TEXT NOT NULL, amount BIGINT NOT NULL, created BIGINT NOT NULL, pdf MEDIUMBLOB NOT NULL, UNIQUE (year, seq));
+ALTER TABLE elements ADD COLUMN address TEXT NULL;
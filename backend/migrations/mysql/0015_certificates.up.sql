@@ -0,0 +1 @@
+CREATE TABLE certificates (id INT NOT NULL KEY auto_increment, serial VARCHAR(16) NOT NULL UNIQUE, mid VARCHAR(16) NOT NULL, name TEXT NOT NULL, issued BIGINT NOT NULL, revoked BIGINT NULL, INDEX (mid));
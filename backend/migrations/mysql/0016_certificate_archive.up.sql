@@ -0,0 +1 @@
+ALTER TABLE certificates ADD COLUMN hash CHAR(64) NULL;
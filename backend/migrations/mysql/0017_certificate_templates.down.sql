@@ -0,0 +1 @@
+DROP TABLE certificate_templates;
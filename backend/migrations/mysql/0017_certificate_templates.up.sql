@@ -0,0 +1 @@
+CREATE TABLE certificate_templates (id INT NOT NULL KEY auto_increment, type VARCHAR(64) NOT NULL DEFAULT "", variant VARCHAR(16) NOT NULL, version INT NOT NULL, content MEDIUMTEXT NOT NULL, active TINYINT NOT NULL DEFAULT 0, created BIGINT NOT NULL, UNIQUE (type, variant, version));
@@ -0,0 +1 @@
+ALTER TABLE elements ADD COLUMN sponsor CHAR(64) NULL, ADD INDEX elements_sponsor (sponsor);
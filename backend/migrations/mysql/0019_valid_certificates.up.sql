@@ -0,0 +1,2 @@
+UPDATE certificates AS older JOIN certificates AS newer ON newer.mid = older.mid AND newer.id > older.id AND newer.revoked IS NULL SET older.revoked = newer.issued WHERE older.revoked IS NULL;
+ALTER TABLE certificates ADD COLUMN valid_mid VARCHAR(16) AS (IF(revoked IS NULL, mid, NULL)) STORED, ADD UNIQUE INDEX certificates_valid (valid_mid);
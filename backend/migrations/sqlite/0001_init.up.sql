@@ -0,0 +1,2 @@
+CREATE TABLE IF NOT EXISTS elements (mid VARCHAR(16) NOT NULL PRIMARY KEY, name TEXT NOT NULL DEFAULT '', mail TEXT, reservation TEXT NULL DEFAULT (datetime('now')));
+CREATE TABLE IF NOT EXISTS users (uid INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, password BLOB NOT NULL, tid INTEGER NOT NULL DEFAULT 0);
@@ -0,0 +1 @@
+ALTER TABLE elements ADD COLUMN confirmation CHAR(32) NULL;
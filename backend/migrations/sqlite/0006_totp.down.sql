@@ -0,0 +1,4 @@
+DROP TABLE recovery_codes;
+ALTER TABLE users DROP COLUMN step;
+ALTER TABLE users DROP COLUMN enrollment;
+ALTER TABLE users DROP COLUMN totp;
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN totp TEXT NULL;
+ALTER TABLE users ADD COLUMN enrollment TEXT NULL;
+ALTER TABLE users ADD COLUMN step INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE recovery_codes (uid INTEGER NOT NULL REFERENCES users (uid) ON DELETE CASCADE, code TEXT NOT NULL, PRIMARY KEY (uid, code));
@@ -0,0 +1 @@
+CREATE TABLE login_throttle (kind TEXT NOT NULL, subject TEXT NOT NULL, failures INTEGER NOT NULL DEFAULT 0, last INTEGER NOT NULL, locked INTEGER NOT NULL DEFAULT 0, PRIMARY KEY (kind, subject));
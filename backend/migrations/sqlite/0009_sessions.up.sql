@@ -0,0 +1 @@
+CREATE TABLE sessions (jti TEXT NOT NULL PRIMARY KEY, uid INTEGER NOT NULL REFERENCES users (uid) ON DELETE CASCADE, created INTEGER NOT NULL, used INTEGER NOT NULL, ip TEXT NOT NULL DEFAULT '', agent TEXT NOT NULL DEFAULT '');
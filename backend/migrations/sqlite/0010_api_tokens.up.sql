@@ -0,0 +1 @@
+CREATE TABLE api_tokens (id TEXT NOT NULL PRIMARY KEY, uid INTEGER NOT NULL REFERENCES users (uid) ON DELETE CASCADE, name TEXT NOT NULL, hash TEXT NOT NULL UNIQUE, scopes TEXT NOT NULL, created INTEGER NOT NULL, expires INTEGER NULL, used INTEGER NOT NULL DEFAULT 0, ip TEXT NOT NULL DEFAULT '');
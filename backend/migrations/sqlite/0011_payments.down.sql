@@ -0,0 +1,5 @@
+ALTER TABLE elements DROP COLUMN reference;
+ALTER TABLE elements DROP COLUMN method;
+ALTER TABLE elements DROP COLUMN paid;
+ALTER TABLE elements DROP COLUMN received;
+ALTER TABLE elements DROP COLUMN due;
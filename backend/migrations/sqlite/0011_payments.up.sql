@@ -0,0 +1,5 @@
+ALTER TABLE elements ADD COLUMN due INTEGER NULL;
+ALTER TABLE elements ADD COLUMN received INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE elements ADD COLUMN paid TEXT NULL;
+ALTER TABLE elements ADD COLUMN method TEXT NULL;
+ALTER TABLE elements ADD COLUMN reference TEXT NULL;
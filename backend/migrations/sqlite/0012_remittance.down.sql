@@ -0,0 +1,2 @@
+DROP INDEX elements_remittance;
+ALTER TABLE elements DROP COLUMN remittance;
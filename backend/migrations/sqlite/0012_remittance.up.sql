@@ -0,0 +1,2 @@
+ALTER TABLE elements ADD COLUMN remittance TEXT NULL;
+CREATE UNIQUE INDEX elements_remittance ON elements (remittance);
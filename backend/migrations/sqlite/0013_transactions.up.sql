@@ -0,0 +1 @@
+CREATE TABLE transactions (id INTEGER PRIMARY KEY AUTOINCREMENT, hash TEXT NOT NULL UNIQUE, booked TEXT NOT NULL, amount INTEGER NOT NULL, name TEXT NOT NULL DEFAULT '', iban TEXT NOT NULL DEFAULT '', purpose TEXT NOT NULL DEFAULT '', mid TEXT NULL, reasons TEXT NOT NULL DEFAULT '', status TEXT NOT NULL DEFAULT 'open', created INTEGER NOT NULL);
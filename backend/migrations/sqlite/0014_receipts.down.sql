@@ -0,0 +1,2 @@
+DROP TABLE receipts;
+ALTER TABLE elements DROP COLUMN address;
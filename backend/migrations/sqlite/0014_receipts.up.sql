@@ -0,0 +1,2 @@
+ALTER TABLE elements ADD COLUMN address TEXT NULL;
+CREATE TABLE receipts (id INTEGER PRIMARY KEY AUTOINCREMENT, year INTEGER NOT NULL, seq INTEGER NOT NULL, number TEXT NOT NULL UNIQUE, kind TEXT NOT NULL, mids TEXT NOT NULL, name TEXT NOT NULL, mail TEXT NULL, address TEXT NOT NULL, amount INTEGER NOT NULL, created INTEGER NOT NULL, pdf BLOB NOT NULL, UNIQUE (year, seq));
@@ -0,0 +1,2 @@
+CREATE TABLE certificates (id INTEGER PRIMARY KEY AUTOINCREMENT, serial TEXT NOT NULL UNIQUE, mid TEXT NOT NULL, name TEXT NOT NULL, issued INTEGER NOT NULL, revoked INTEGER NULL);
+CREATE INDEX certificates_mid ON certificates (mid);
@@ -0,0 +1 @@
+ALTER TABLE certificates ADD COLUMN hash TEXT NULL;
@@ -0,0 +1 @@
+CREATE TABLE certificate_templates (id INTEGER PRIMARY KEY AUTOINCREMENT, type TEXT NOT NULL DEFAULT '', variant TEXT NOT NULL, version INTEGER NOT NULL, content TEXT NOT NULL, active INTEGER NOT NULL DEFAULT 0, created INTEGER NOT NULL, UNIQUE (type, variant, version));
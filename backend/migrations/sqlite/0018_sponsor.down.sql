@@ -0,0 +1,2 @@
+DROP INDEX elements_sponsor;
+ALTER TABLE elements DROP COLUMN sponsor;
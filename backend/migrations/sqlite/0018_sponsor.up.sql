@@ -0,0 +1,2 @@
+ALTER TABLE elements ADD COLUMN sponsor TEXT NULL;
+CREATE INDEX elements_sponsor ON elements (sponsor);
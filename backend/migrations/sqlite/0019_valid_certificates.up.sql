@@ -0,0 +1,2 @@
+UPDATE certificates SET revoked = (SELECT MAX(newer.issued) FROM certificates AS newer WHERE newer.mid = certificates.mid AND newer.id > certificates.id AND newer.revoked IS NULL) WHERE revoked IS NULL AND EXISTS (SELECT 1 FROM certificates AS newer WHERE newer.mid = certificates.mid AND newer.id > certificates.id AND newer.revoked IS NULL);
+CREATE UNIQUE INDEX certificates_valid ON certificates (mid) WHERE revoked IS NULL;
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// delivery-states of the mails in the outbox
+const (
+	outboxQueued = "queued"
+	outboxDead   = "dead"
+)
+
+// new mail for the outbox
+type OutboxEntryDB struct {
+	Sender     string
+	Recipients string
+	Subject    string
+	Message    []byte
+	Due        int64
+	Created    int64
+}
+
+// mail in the outbox without its message
+type OutboxDB struct {
+	Id         int    `json:"id"`
+	Sender     string `json:"sender"`
+	Recipients string `json:"recipients"`
+	Subject    string `json:"subject"`
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	Due        int64  `json:"due"`
+	Reason     string `json:"reason"`
+	Created    int64  `json:"created"`
+}
+
+// mail in the outbox with everything needed for the delivery
+type OutboxMessageDB struct {
+	Id         int
+	Sender     string
+	Recipients string
+	Message    []byte
+	Attempts   int
+}
+
+// delivery-state of a mail in the outbox
+type OutboxStateDB struct {
+	Status   string
+	Attempts int
+	Due      int64
+	Reason   string
+}
+
+// renders a mail and adds it to the outbox
+func enqueueMail(email *mail.Email, subject string) error {
+	if email.Error != nil {
+		return email.Error
+	}
+
+	now := time.Now().Unix()
+
+	return store.EnqueueMail(OutboxEntryDB{
+		Sender:     config.Mail.User,
+		Recipients: strings.Join(email.GetRecipients(), ","),
+		Subject:    subject,
+		Message:    []byte(email.GetMessage()),
+		Due:        now,
+		Created:    now,
+	})
+}
+
+// sends a single mail from the outbox
+func deliverMail(message OutboxMessageDB) error {
+	return mailer.send(message.Sender, strings.Split(message.Recipients, ","), message.Message)
+}
+
+// returns the delay before the next delivery-attempt
+func outboxBackoff(attempts int) time.Duration {
+	delay := config.Outbox.Backoff
+
+	for ii := 1; ii < attempts && delay < config.Outbox.MaxBackoff; ii++ {
+		delay *= 2
+	}
+
+	return min(delay, config.Outbox.MaxBackoff)
+}
+
+// tries to deliver all due mails from the outbox
+func processOutbox() {
+	messages, err := store.GetDueMails(time.Now().Unix())
+
+	if err != nil {
+		logger.Error().Msgf("can't get mails from outbox: %v", err)
+
+		return
+	}
+
+	for _, message := range messages {
+		if err := deliverMail(message); err == nil {
+			if _, err := store.DeleteMail(message.Id); err != nil {
+				logger.Error().Msgf("can't remove delivered mail %d from outbox: %v", message.Id, err)
+			} else {
+				logger.Debug().Msgf("delivered mail %d to %q", message.Id, message.Recipients)
+			}
+		} else {
+			state := OutboxStateDB{
+				Status:   outboxQueued,
+				Attempts: message.Attempts + 1,
+				Reason:   err.Error(),
+			}
+
+			if state.Attempts >= config.Mail.Outbox.MaxAttempts {
+				state.Status = outboxDead
+
+				logger.Error().Msgf("giving up delivery of mail %d to %q after %d attempts: %v", message.Id, message.Recipients, state.Attempts, err)
+			} else {
+				state.Due = time.Now().Add(outboxBackoff(state.Attempts)).Unix()
+
+				logger.Warn().Msgf("can't deliver mail %d to %q (attempt %d): %v", message.Id, message.Recipients, state.Attempts, err)
+			}
+
+			if _, err := store.UpdateMail(message.Id, state); err != nil {
+				logger.Error().Msgf("can't update mail %d in outbox: %v", message.Id, err)
+			}
+		}
+	}
+}
+
+// delivers the mails from the outbox in the background
+func runOutbox() {
+	for {
+		processOutbox()
+
+		time.Sleep(config.Outbox.Interval)
+	}
+}
+
+// handles get-requests for the mails in the outbox
+func getOutbox(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if status := c.Query("status"); status != "" && status != outboxQueued && status != outboxDead {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid status"
+
+		logger.Info().Msgf("invalid outbox-status: %q", status)
+	} else if mails, err := store.GetMails(status); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get mails from outbox: %v", err)
+	} else {
+		response.Data = mails
+	}
+
+	return response
+}
+
+// handles post-requests to retry the delivery of a mail
+func postOutbox(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if ok, err := store.UpdateMail(id, OutboxStateDB{Status: outboxQueued, Due: time.Now().Unix()}); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't requeue mail %d: %v", id, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "mail doesn't exist"
+
+		logger.Info().Msgf("can't requeue mail %d: mail doesn't exist", id)
+	} else {
+		logger.Info().Msgf("requeued mail %d", id)
+
+		response = getOutbox(c)
+	}
+
+	return response
+}
+
+// handles delete-requests to discard a mail
+func deleteOutbox(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if ok, err := store.DeleteMail(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't discard mail %d: %v", id, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "mail doesn't exist"
+
+		logger.Info().Msgf("can't discard mail %d: mail doesn't exist", id)
+	} else {
+		logger.Info().Msgf("discarded mail %d", id)
+
+		response = getOutbox(c)
+	}
+
+	return response
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	mail "github.com/xhit/go-simple-mail/v2"
+)
+
+// template-data of the password-reset-mail
+type PasswordResetTemplateData struct {
+	Name       string
+	ResetLink  string
+	Expiration string
+}
+
+// creates the link to the admin-panel to reset the password
+func passwordResetLink(token string) string {
+	return fmt.Sprintf("%s/admin.html?reset=%s", strings.TrimSuffix(config.Server.URL, "/"), url.QueryEscape(token))
+}
+
+// queues the mail with the password-reset-link for a user
+func sendPasswordResetMail(user *UserDB, resetLink string) error {
+	email := mail.NewMSG()
+
+	templateData := PasswordResetTemplateData{
+		Name:       user.Name,
+		ResetLink:  resetLink,
+		Expiration: config.ResetExpire.String(),
+	}
+
+	if subject, err := parseTemplate("templates/password_reset_mail", templateData); err != nil {
+		return err
+	} else if bodyHTML, err := parseHTMLTemplate("templates/password_reset_mail.html", templateData); err != nil {
+		return err
+	} else if bodyPlain, err := parseHTMLTemplate("templates/password_reset_mail.txt", templateData); err != nil {
+		return err
+	} else {
+		email.SetFrom(fmt.Sprintf("Klimaplus-Patenschaft <%s>", config.Mail.User)).AddTo(*user.Email).SetSubject(subject)
+
+		email.SetBody(mail.TextPlain, bodyPlain)
+
+		email.AddAlternative(mail.TextHTML, bodyHTML)
+
+		return enqueueMail(email, subject)
+	}
+}
+
+// returns the user identified by its name or its e-mail-address, nil if there is none
+func getUserByNameOrEmail(name string) (*UserDB, error) {
+	if user, err := store.GetUserByName(name); err != nil || user != nil {
+		return user, err
+	} else {
+		return store.GetUserByEmail(name)
+	}
+}
+
+// handles requests for a password-reset-mail
+func handleForgotPassword(c *fiber.Ctx) error {
+	logger.Debug().Msgf("HTTP %s request: %q", c.Method(), c.OriginalURL())
+
+	var response responseMessage
+
+	body := struct {
+		User string `json:"user"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil || body.User == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse password-reset-body: %v", err)
+	} else if response = checkPasswordResetThrottle(c); response.Status >= 400 {
+		// too many requests from this ip-address
+	} else if user, err := getUserByNameOrEmail(body.User); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get users from the database: %v", err)
+	} else {
+		// every request is counted, so the reset-mails can't be triggered without limit
+		recordPasswordResetRequest(c, body.User)
+
+		// answer the same way for unknown users, so they can't be enumerated
+		response.Status = fiber.StatusOK
+		response.Message = "if the user exists and has an e-mail-address, a reset-link was sent"
+
+		if user == nil {
+			logger.Info().Msgf("password-reset requested for unknown user %q", body.User)
+		} else if user.Email == nil {
+			logger.Info().Msgf("password-reset requested for user with uid = %d without e-mail-address", user.Uid)
+		} else if token, err := config.signPasswordResetJWT(user.Uid, user.Tid); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't create password-reset-link: %v", err)
+		} else if err := sendPasswordResetMail(user, passwordResetLink(token)); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't queue password-reset-mail: %v", err)
+		} else {
+			logger.Info().Msgf("sent password-reset-link to user with uid = %d", user.Uid)
+		}
+	}
+
+	return response.send(c)
+}
+
+// handles requests to set a new password with a password-reset-link
+func handleResetPassword(c *fiber.Ctx) error {
+	logger.Debug().Msgf("HTTP %s request: %q", c.Method(), c.OriginalURL())
+
+	var response responseMessage
+	var payload PasswordResetPayload
+
+	body := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse password-reset-body: %v", err)
+	} else if token, err := jwt.ParseWithClaims(body.Token, &payload, config.verificationKey, jwt.WithAudience(passwordResetAudience)); err != nil || !token.Valid {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid or expired reset-link"
+
+		logger.Info().Msgf("invalid password-reset: %v", err)
+	} else if !validatePassword(body.Password) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid password"
+
+		logger.Info().Msg("invalid password")
+	} else if user, err := store.GetUser(payload.Uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get user with uid = %d from database: %v", payload.Uid, err)
+	} else if user == nil || user.Tid != payload.Tid {
+		// the token-id changes with the password, so every link can only be used once
+		response.Status = fiber.StatusGone
+		response.Message = "reset-link was already used"
+
+		logger.Info().Msgf("password-reset-link for user with uid = %d is no longer valid", payload.Uid)
+	} else if response = changePassword(user.Uid, body.Password); response.Status == fiber.StatusOK {
+		// the user proved the access to the mailbox, so the lockout isn't needed anymore
+		clearLoginFailures(user)
+
+		logger.Info().Msgf("reset password for user with uid = %d", user.Uid)
+	}
+
+	return response.send(c)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"cmp"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+)
+
+// available payment-methods
+const (
+	paymentTransfer = "transfer"
+	paymentCash     = "cash"
+	paymentOther    = "other"
+)
+
+var paymentMethods = []string{paymentTransfer, paymentCash, paymentOther}
+
+// maximum length of a payment-reference, as in a sepa-transfer
+const paymentReferenceLength = 140
+
+// bank-account for the transfers of the sponsors
+type BankAccount struct {
+	Beneficiary string
+	IBAN        string
+	BIC         string
+}
+
+// maximum length of the beneficiary-name in a sepa-transfer
+const beneficiaryLength = 70
+
+// characters of the random part of the remittance-references, without easily confused ones
+const remittanceAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// number of random characters in a remittance-reference
+const remittanceRandomLength = 8
+
+// maximum length of a creditor-reference, including "RF" and the check-digits
+const remittanceLength = 25
+
+// validates the bank-account from the config, an empty account disables the giro-codes
+func parseBankAccount(beneficiary, iban, bic string) (BankAccount, error) {
+	account := BankAccount{
+		Beneficiary: strings.TrimSpace(beneficiary),
+		IBAN:        strings.ToUpper(strings.ReplaceAll(iban, " ", "")),
+		BIC:         strings.ToUpper(strings.ReplaceAll(bic, " ", "")),
+	}
+
+	if account == (BankAccount{}) {
+		return account, nil
+	} else if account.Beneficiary == "" || len(account.Beneficiary) > beneficiaryLength {
+		return BankAccount{}, fmt.Errorf("beneficiary must have 1 to %d characters", beneficiaryLength)
+	} else if !validateIBAN(account.IBAN) {
+		return BankAccount{}, fmt.Errorf("invalid iban %q", iban)
+	} else if account.BIC != "" && len(account.BIC) != 8 && len(account.BIC) != 11 {
+		return BankAccount{}, fmt.Errorf("invalid bic %q", bic)
+	} else {
+		return account, nil
+	}
+}
+
+// calculates the remainder of an alphanumeric string modulo 97 (ISO 7064), letters count as 10 to 35
+func mod97(s string) (int, bool) {
+	remainder := 0
+
+	for _, char := range s {
+		switch {
+		case char >= '0' && char <= '9':
+			remainder = (remainder*10 + int(char-'0')) % 97
+		case char >= 'A' && char <= 'Z':
+			remainder = (remainder*100 + int(char-'A') + 10) % 97
+		default:
+			return 0, false
+		}
+	}
+
+	return remainder, true
+}
+
+// checks the country-code, length and check-digits of an iban without spaces
+func validateIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 || iban[0] < 'A' || iban[0] > 'Z' || iban[1] < 'A' || iban[1] > 'Z' {
+		return false
+	} else {
+		remainder, ok := mod97(iban[4:] + iban[:4])
+
+		return ok && remainder == 1
+	}
+}
+
+// formats an iban in groups of four characters
+func formatIBAN(iban string) string {
+	var groups []string
+
+	for ii := 0; ii < len(iban); ii += 4 {
+		groups = append(groups, iban[ii:min(ii+4, len(iban))])
+	}
+
+	return strings.Join(groups, " ")
+}
+
+// creates a unique creditor-reference (ISO 11649) for a reservation from the element-id and random characters, e.g. "RF84MA12K7QX3PZN"
+func newRemittanceReference(mid string) (string, error) {
+	// keep the letters and digits of the element-id, so the treasurer can recognize it
+	body := strings.Map(func(char rune) rune {
+		if (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') {
+			return char
+		} else {
+			return -1
+		}
+	}, strings.ToUpper(mid))
+
+	body = body[:min(len(body), remittanceLength-4-remittanceRandomLength)]
+
+	for range remittanceRandomLength {
+		if n, err := rand.Int(rand.Reader, big.NewInt(int64(len(remittanceAlphabet)))); err != nil {
+			return "", err
+		} else {
+			body += string(remittanceAlphabet[n.Int64()])
+		}
+	}
+
+	remainder, _ := mod97(body + "RF00")
+
+	return fmt.Sprintf("RF%02d%s", 98-remainder, body), nil
+}
+
+// checks the check-digits of a creditor-reference, spaces are ignored
+func validateRemittanceReference(reference string) bool {
+	reference = strings.ToUpper(strings.ReplaceAll(reference, " ", ""))
+
+	if len(reference) < 5 || len(reference) > remittanceLength || !strings.HasPrefix(reference, "RF") {
+		return false
+	} else {
+		remainder, ok := mod97(reference[4:] + reference[:4])
+
+		return ok && remainder == 1
+	}
+}
+
+// creates the payload of an EPC069-12 qr-code ("GiroCode") for a sepa-transfer of an amount in cents
+func giroCodePayload(account BankAccount, amount int64, reference string) string {
+	amountField := ""
+
+	// the amount is optional, but has to be at least one cent
+	if amount > 0 {
+		amountField = fmt.Sprintf("EUR%d.%02d", amount/100, amount%100)
+	}
+
+	return strings.Join([]string{
+		"BCD",
+		"002",
+		// utf-8
+		"1",
+		"SCT",
+		account.BIC,
+		account.Beneficiary,
+		account.IBAN,
+		amountField,
+		// purpose-code
+		"",
+		// structured creditor-reference
+		reference,
+	}, "\n")
+}
+
+// renders the giro-code for a transfer as png, nil if no bank-account is configured
+func giroCodePNG(amount int64, reference string) ([]byte, error) {
+	if config.BankAccount.IBAN == "" {
+		return nil, nil
+	} else {
+		// the specification requires the error-correction-level "M"
+		return qrcode.Encode(giroCodePayload(config.BankAccount, amount, reference), qrcode.Medium, 256)
+	}
+}
+
+// payment-data of an element in the database, amounts in cents
+type PaymentDB struct {
+	Due       *int64
+	Received  int64
+	Paid      *string
+	Method    *string
+	Reference *string
+}
+
+// returns the price of an element in cents, nil if the element isn't part of the catalogue
+func elementPrice(mid string) *int64 {
+	if elementType, ok := lookupElement(mid); !ok {
+		return nil
+	} else {
+		price := int64(math.Round(elementType.Price * 100))
+
+		return &price
+	}
+}
+
+// returns the amount due for an element in cents, elements reserved before the pricing use the current price
+func amountDue(mid string, due *int64) int64 {
+	if due != nil {
+		return *due
+	} else if price := elementPrice(mid); price != nil {
+		return *price
+	} else {
+		return 0
+	}
+}
+
+// checks wether a payment-date is valid
+func validatePaymentDate(date string) bool {
+	_, err := time.Parse(time.DateOnly, date)
+
+	return err == nil
+}
+
+// checks wether a payment-reference is valid, it is optional
+func validatePaymentReference(reference *string) bool {
+	return reference == nil || len(*reference) <= paymentReferenceLength
+}
+
+// responds with the payment-data of an element
+func getPayment(mid string) responseMessage {
+	var response responseMessage
+
+	if element, err := store.GetElement(mid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get element %q from database: %v", mid, err)
+	} else {
+		response.Data = element
+	}
+
+	return response
+}
+
+// handles post-requests to record a payment for a reservation or sponsorship
+func postPayments(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Amount    int64   `json:"amount"`
+		Date      string  `json:"date"`
+		Method    string  `json:"method"`
+		Reference *string `json:"reference"`
+	}{}
+
+	mid := c.Query("mid")
+
+	if mid == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid mid"
+
+		logger.Info().Msg("query doesn't include valid mid")
+	} else if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse payment-body: %v", err)
+	} else if body.Amount <= 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid amount"
+
+		logger.Info().Msgf("invalid payment-amount %d", body.Amount)
+
+		// payments are recorded for today, if no date is given
+	} else if date := cmp.Or(body.Date, time.Now().Format(time.DateOnly)); !validatePaymentDate(date) || !slices.Contains(paymentMethods, body.Method) || !validatePaymentReference(body.Reference) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid payment"
+
+		logger.Info().Msgf("invalid payment for %q", mid)
+	} else if ok, err := store.RecordPayment(mid, body.Amount, date, body.Method, body.Reference); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't record payment for %q: %v", mid, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "no reservation found"
+
+		logger.Info().Msgf("no element-reservation for %q", mid)
+	} else {
+		logger.Info().Msgf("user with uid = %d recorded payment of %d cents for %q", getSession(c).Uid, body.Amount, mid)
+
+		response = getPayment(mid)
+	}
+
+	return response
+}
+
+// handles patch-requests to correct the payment-data of a reservation or sponsorship, a missing due-amount resets it to the current price
+func patchPayments(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Due       *int64  `json:"due"`
+		Received  int64   `json:"received"`
+		Date      *string `json:"date"`
+		Method    *string `json:"method"`
+		Reference *string `json:"reference"`
+	}{}
+
+	mid := c.Query("mid")
+
+	if mid == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid mid"
+
+		logger.Info().Msg("query doesn't include valid mid")
+	} else if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse payment-body: %v", err)
+	} else if (body.Due != nil && *body.Due < 0) || body.Received < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid amount"
+
+		logger.Info().Msg("invalid payment-amount")
+	} else if (body.Date != nil && !validatePaymentDate(*body.Date)) || (body.Method != nil && !slices.Contains(paymentMethods, *body.Method)) || !validatePaymentReference(body.Reference) {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid payment"
+
+		logger.Info().Msgf("invalid payment for %q", mid)
+	} else if ok, err := store.SetPayment(mid, PaymentDB{
+		Due:       body.Due,
+		Received:  body.Received,
+		Paid:      body.Date,
+		Method:    body.Method,
+		Reference: body.Reference,
+	}); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't set payment for %q: %v", mid, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "no reservation found"
+
+		logger.Info().Msgf("no element-reservation for %q", mid)
+	} else {
+		logger.Info().Msgf("user with uid = %d corrected payment for %q", getSession(c).Uid, mid)
+
+		response = getPayment(mid)
+	}
+
+	return response
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIBAN(t *testing.T) {
+	tests := map[string]bool{
+		"DE89370400440532013000":          true,
+		"GB82WEST12345698765432":          true,
+		"NL91ABNA0417164300":              true,
+		"DE89370400440532013001":          false,
+		"DE88370400440532013000":          false,
+		"de89370400440532013000":          false,
+		"DE8937040044":                    false,
+		"DE89 3704 0044 0532 0130 00":     false,
+		"1289370400440532013000":          false,
+		"DE89370400440532013000000000000": false,
+	}
+
+	for iban, valid := range tests {
+		if result := validateIBAN(iban); result != valid {
+			t.Errorf("validateIBAN(%q) = %v, expected %v", iban, result, valid)
+		}
+	}
+}
+
+func TestRemittanceReference(t *testing.T) {
+	// examples of ISO 11649
+	tests := map[string]bool{
+		"RF18539007547034":          true,
+		"RF18 5390 0754 7034":       true,
+		"rf18539007547034":          true,
+		"RF712348231":               true,
+		"RF19539007547034":          false,
+		"RF18539007547035":          false,
+		"XX18539007547034":          false,
+		"RF1":                       false,
+		"RF18ABCDEFGHIJKLMNOPQRSTU": false,
+	}
+
+	for reference, valid := range tests {
+		if result := validateRemittanceReference(reference); result != valid {
+			t.Errorf("validateRemittanceReference(%q) = %v, expected %v", reference, result, valid)
+		}
+	}
+
+	references := map[string]bool{}
+
+	for range 100 {
+		reference, err := newRemittanceReference("pv-kirchendach12")
+
+		if err != nil {
+			t.Fatalf("can't create remittance-reference: %v", err)
+		} else if !validateRemittanceReference(reference) {
+			t.Errorf("created remittance-reference %q is invalid", reference)
+		} else if len(reference) > remittanceLength {
+			t.Errorf("remittance-reference %q is longer than %d characters", reference, remittanceLength)
+		} else if !strings.HasPrefix(reference[4:], "PVKIRCHENDAC") {
+			t.Errorf("remittance-reference %q doesn't contain the element-id", reference)
+		}
+
+		references[reference] = true
+	}
+
+	if len(references) < 100 {
+		t.Errorf("only %d of 100 remittance-references are unique", len(references))
+	}
+}
+
+func TestGiroCodePayload(t *testing.T) {
+	account := BankAccount{Beneficiary: "Kirchengemeinde Musterstadt", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"}
+
+	tests := []struct {
+		amount    int64
+		reference string
+		payload   string
+	}{
+		{125050, "RF18539007547034", "BCD\n002\n1\nSCT\nCOBADEFFXXX\nKirchengemeinde Musterstadt\nDE89370400440532013000\nEUR1250.50\n\nRF18539007547034"},
+		{5, "RF18539007547034", "BCD\n002\n1\nSCT\nCOBADEFFXXX\nKirchengemeinde Musterstadt\nDE89370400440532013000\nEUR0.05\n\nRF18539007547034"},
+		{0, "", "BCD\n002\n1\nSCT\nCOBADEFFXXX\nKirchengemeinde Musterstadt\nDE89370400440532013000\n\n\n"},
+	}
+
+	for _, test := range tests {
+		if payload := giroCodePayload(account, test.amount, test.reference); payload != test.payload {
+			t.Errorf("giroCodePayload(%d, %q) = %q, expected %q", test.amount, test.reference, payload, test.payload)
+		}
+	}
+}
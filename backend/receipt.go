@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+)
+
+// kinds of donation-receipts
+const (
+	receiptSingle     = "single"
+	receiptCollective = "collective"
+)
+
+// maximum length of the postal address of a sponsor
+const addressLength = 300
+
+// new donation-receipt for the database, number and sequence are assigned by the store
+type ReceiptEntryDB struct {
+	Year   int
+	Seq    int
+	Number string
+	Kind   string
+	// comma-separated ids of the receipted elements
+	Mids    string
+	Name    string
+	Mail    *string
+	Address string
+	Amount  int64
+	Created int64
+	Pdf     []byte
+}
+
+// archived donation-receipt without its pdf
+type ReceiptDB struct {
+	Id      int     `json:"id"`
+	Year    int     `json:"year"`
+	Number  string  `json:"number"`
+	Kind    string  `json:"kind"`
+	Mids    string  `json:"mids"`
+	Name    string  `json:"name"`
+	Mail    *string `json:"mail"`
+	Address string  `json:"address"`
+	Amount  int64   `json:"amount"`
+	Created int64   `json:"created"`
+}
+
+// archived donation-receipt with its pdf
+type ReceiptFileDB struct {
+	Number string
+	Pdf    []byte
+}
+
+// single donation on a receipt
+type ReceiptDonation struct {
+	Element     string
+	Date        string
+	Amount      string
+	AmountWords string
+}
+
+// template-data of the donation-receipts
+type ReceiptTemplateData struct {
+	Issuer ReceiptYaml
+	Number string
+	Date   string
+	// postal address of the sponsor, including the name
+	Address     string
+	Amount      string
+	AmountWords string
+	Collective  bool
+	Year        int
+	Donations   []ReceiptDonation
+}
+
+// built-in template of the receipts after the official form, lines starting with "# " are headings
+const defaultReceiptTemplate = `# Bestätigung über Geldzuwendungen{{ if .Collective }} (Sammelbestätigung){{ end }}
+im Sinne des § 10b des Einkommensteuergesetzes an eine der in § 5 Abs. 1 Nr. 9 des Körperschaftsteuergesetzes bezeichneten Körperschaften, Personenvereinigungen oder Vermögensmassen
+
+Aussteller: {{ .Issuer.Name }}, {{ .Issuer.Address }}
+Nummer: {{ .Number }}
+
+Name und Anschrift des Zuwendenden:
+{{ .Address }}
+
+{{ if .Collective }}Gesamtbetrag der Zuwendungen im Jahr {{ .Year }}{{ else }}Betrag der Zuwendung{{ end }}: {{ .Amount }}
+in Buchstaben: {{ .AmountWords }}
+{{ if .Collective }}
+Datum der Zuwendung | Art der Zuwendung | Verzicht auf die Erstattung von Aufwendungen | Betrag
+{{ range .Donations }}{{ .Date }} | Geldzuwendung ({{ .Element }}) | nein | {{ .Amount }}
+{{ end }}{{ else }}Tag der Zuwendung: {{ (index .Donations 0).Date }}
+Es handelt sich nicht um den Verzicht auf Erstattung von Aufwendungen.
+{{ end }}
+Wir sind wegen Förderung {{ .Issuer.Purpose }} nach dem Freistellungsbescheid bzw. nach der Anlage zum Körperschaftsteuerbescheid des Finanzamtes {{ .Issuer.TaxOffice }}, StNr. {{ .Issuer.TaxNumber }}, vom {{ .Issuer.NoticeDate }} nach § 5 Abs. 1 Nr. 9 des Körperschaftsteuergesetzes von der Körperschaftsteuer und nach § 3 Nr. 6 des Gewerbesteuergesetzes von der Gewerbesteuer befreit.
+
+Es wird bestätigt, dass die Zuwendung nur zur Förderung {{ .Issuer.Purpose }} verwendet wird.
+{{ if .Collective }}
+Es wird bestätigt, dass über die in der Gesamtsumme enthaltenen Zuwendungen keine weiteren Bestätigungen, weder formelle Zuwendungsbestätigungen noch Beitragsquittungen oder Ähnliches ausgestellt wurden und werden.
+{{ end }}
+{{ .Issuer.Place }}, den {{ .Date }}
+
+{{ .Issuer.Signatory }}
+
+Hinweis: Wer vorsätzlich oder grob fahrlässig eine unrichtige Zuwendungsbestätigung erstellt oder veranlasst, dass Zuwendungen nicht zu den in der Zuwendungsbestätigung angegebenen steuerbegünstigten Zwecken verwendet werden, haftet für die entgangene Steuer (§ 10b Abs. 4 EStG, § 9 Abs. 3 KStG, § 9 Nr. 5 GewStG).
+`
+
+// number-words for the amounts on the receipts
+var (
+	germanOnes  = [10]string{"", "ein", "zwei", "drei", "vier", "fünf", "sechs", "sieben", "acht", "neun"}
+	germanTeens = [10]string{"zehn", "elf", "zwölf", "dreizehn", "vierzehn", "fünfzehn", "sechzehn", "siebzehn", "achtzehn", "neunzehn"}
+	germanTens  = [10]string{"", "", "zwanzig", "dreißig", "vierzig", "fünfzig", "sechzig", "siebzig", "achtzig", "neunzig"}
+)
+
+// writes a number below 1000 in german words, e.g. "dreihunderteinundzwanzig"
+func germanHundreds(n int64) string {
+	var words string
+
+	if n >= 100 {
+		words = germanOnes[n/100] + "hundert"
+		n %= 100
+	}
+
+	switch {
+	case n >= 20 && n%10 != 0:
+		return words + germanOnes[n%10] + "und" + germanTens[n/10]
+	case n >= 20:
+		return words + germanTens[n/10]
+	case n >= 10:
+		return words + germanTeens[n-10]
+	default:
+		return words + germanOnes[n]
+	}
+}
+
+// writes a number in german words as used before a noun, e.g. "eintausendeinhundertein" for "Euro"
+func germanNumberWords(n int64) string {
+	if n == 0 {
+		return "null"
+	}
+
+	var parts []string
+
+	if millions := n / 1_000_000; millions == 1 {
+		parts = append(parts, "eine Million")
+	} else if millions > 1 {
+		parts = append(parts, germanNumberWords(millions)+" Millionen")
+	}
+
+	var words string
+
+	if thousands := n / 1000 % 1000; thousands > 0 {
+		words = germanHundreds(thousands) + "tausend"
+	}
+
+	words += germanHundreds(n % 1000)
+
+	if words != "" {
+		parts = append(parts, words)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// writes an amount in cents in german words, e.g. "zwölf Euro und fünfzig Cent"
+func germanAmountWords(cents int64) string {
+	words := germanNumberWords(cents/100) + " Euro"
+
+	if cents%100 != 0 {
+		words += " und " + germanNumberWords(cents%100) + " Cent"
+	}
+
+	return words
+}
+
+// formats an amount in cents the german way
+func formatCents(cents int64) string {
+	return formatEuro(float64(cents) / 100)
+}
+
+// formats a date from the database as "02.01.2006"
+func formatReceiptDate(date string) string {
+	if parsed, err := time.Parse(time.DateOnly, date); err != nil {
+		return date
+	} else {
+		return parsed.Format("02.01.2006")
+	}
+}
+
+// loads the configured receipt-template or the built-in one
+func loadReceiptTemplate() (*template.Template, error) {
+	if config.Receipt.Template != "" {
+		return loadTemplate(config.Receipt.Template)
+	} else {
+		return template.New("receipt").Parse(defaultReceiptTemplate)
+	}
+}
+
+// renders a donation-receipt as pdf
+func createReceipt(data ReceiptTemplateData) ([]byte, error) {
+	var text bytes.Buffer
+
+	if tpl, err := loadReceiptTemplate(); err != nil {
+		return nil, err
+	} else if err := tpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(25, 20, 25)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := pageWidth - left - right
+
+	family, translate := documentFont(pdf)
+
+	for _, line := range strings.Split(text.String(), "\n") {
+		size := 10.0
+
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			size = 14
+			line = heading
+		}
+
+		pdf.SetFont(family, "", size)
+
+		// keep empty lines as spacing
+		if line == "" {
+			pdf.Ln(pdf.PointToUnitConvert(size))
+		} else {
+			pdf.MultiCell(width, pdf.PointToUnitConvert(size)*1.4, translate(line), "", "L", false)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// checks wether a donation of an element can be receipted
+func receiptable(element ElementDB) bool {
+	return element.Received > 0 && element.Paid != nil
+}
+
+// returns the ids of the elements, which already have a receipt
+func receiptedElements() ([]string, error) {
+	if receipts, err := store.GetReceipts(); err != nil {
+		return nil, err
+	} else {
+		mids := []string{}
+
+		for _, receipt := range receipts {
+			mids = append(mids, strings.Split(receipt.Mids, ",")...)
+		}
+
+		return mids, nil
+	}
+}
+
+// creates and archives the receipt for the donations of a sponsor
+func issueReceipt(elements []ElementDB, address string, mail *string, collective bool, year int) (string, error) {
+	now := time.Now()
+
+	data := ReceiptTemplateData{
+		Issuer:     config.Receipt,
+		Date:       now.Format("02.01.2006"),
+		Address:    address,
+		Collective: collective,
+		Year:       year,
+	}
+
+	entry := ReceiptEntryDB{
+		Year:    now.Year(),
+		Kind:    receiptSingle,
+		Name:    elements[0].Name,
+		Mail:    mail,
+		Address: address,
+		Created: now.Unix(),
+	}
+
+	if collective {
+		entry.Kind = receiptCollective
+	}
+
+	mids := make([]string, len(elements))
+
+	for ii, element := range elements {
+		mids[ii] = element.Mid
+		entry.Amount += element.Received
+
+		elementType, _ := lookupElement(element.Mid)
+
+		data.Donations = append(data.Donations, ReceiptDonation{
+			Element:     fmt.Sprintf("%s %s", elementType.Name, getElementID(element.Mid)),
+			Date:        formatReceiptDate(*element.Paid),
+			Amount:      formatCents(element.Received),
+			AmountWords: germanAmountWords(element.Received),
+		})
+	}
+
+	entry.Mids = strings.Join(mids, ",")
+	data.Amount = formatCents(entry.Amount)
+	data.AmountWords = germanAmountWords(entry.Amount)
+
+	return store.AddReceipt(entry, func(number string) ([]byte, error) {
+		data.Number = number
+
+		return createReceipt(data)
+	})
+}
+
+// handles get-requests for the archived donation-receipts
+func getReceipts(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if receipts, err := store.GetReceipts(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get receipts from database: %v", err)
+	} else {
+		response.Data = receipts
+	}
+
+	return response
+}
+
+// handles get-requests for downloading the pdf of an archived donation-receipt
+func getReceiptsPDF(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if id := c.QueryInt("id", -1); id < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid id"
+
+		logger.Info().Msg("query doesn't include valid id")
+	} else if receipt, err := store.GetReceiptFile(id); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get receipt %d from database: %v", id, err)
+	} else if receipt == nil {
+		response.Status = fiber.StatusNotFound
+		response.Message = "receipt doesn't exist"
+
+		logger.Info().Msgf("receipt %d doesn't exist", id)
+	} else {
+		c.Attachment(fmt.Sprintf("receipt.%s.pdf", receipt.Number))
+		c.Send(receipt.Pdf)
+	}
+
+	return response
+}
+
+// pseudonymous id of a sponsor to find the elements for collective receipts, which survives the removal of the mail-address on confirmation
+func sponsorID(mail string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(mail))))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// handles post-requests to issue a donation-receipt, either for a single element or as collective receipt for all donations of a sponsor in a year
+func postReceipts(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	body := struct {
+		Mid  string `json:"mid"`
+		Mail string `json:"mail"`
+		Year int    `json:"year"`
+	}{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse receipt-body: %v", err)
+	} else if (body.Mid == "") == (body.Mail == "") {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "body must include either mid or mail"
+
+		logger.Info().Msg("receipt-body doesn't include either mid or mail")
+	} else if body.Mail != "" && body.Year <= 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid year"
+
+		logger.Info().Msgf("invalid year %d for collective receipt", body.Year)
+	} else if receipted, err := receiptedElements(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get receipts from database: %v", err)
+	} else {
+		var elements []ElementDB
+		collective := body.Mail != ""
+
+		if !collective {
+			if element, err := store.GetElement(body.Mid); err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't get element %q from database: %v", body.Mid, err)
+
+				return response
+			} else if element != nil && receiptable(*element) {
+				elements = []ElementDB{*element}
+			}
+		} else if sponsorElements, err := store.GetElementsByMail(body.Mail); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't get elements of %q from database: %v", body.Mail, err)
+
+			return response
+		} else {
+			// collect the donations of the year, which don't have a receipt yet
+			for _, element := range sponsorElements {
+				if receiptable(element) && strings.HasPrefix(*element.Paid, fmt.Sprintf("%04d-", body.Year)) && !slices.Contains(receipted, element.Mid) {
+					elements = append(elements, element)
+				}
+			}
+		}
+
+		if len(elements) == 0 {
+			response.Status = fiber.StatusNotFound
+			response.Message = "no paid donation found"
+
+			logger.Info().Msgf("no paid donation for receipt of %q", cmp.Or(body.Mid, body.Mail))
+		} else if !collective && slices.Contains(receipted, body.Mid) {
+			response.Status = fiber.StatusConflict
+			response.Message = "donation already has a receipt"
+
+			logger.Info().Msgf("donation for %q already has a receipt", body.Mid)
+		} else if address := addressOf(elements); address == "" {
+			response.Status = fiber.StatusBadRequest
+			response.Message = "sponsor-address missing"
+
+			logger.Info().Msgf("can't issue receipt for %q: sponsor-address missing", elements[0].Mid)
+		} else if number, err := issueReceipt(elements, address, cmp.Or(optionalString(body.Mail), elements[0].Mail), collective, body.Year); err != nil {
+			response.Status = fiber.StatusInternalServerError
+			response.Message = "error while creating receipt"
+
+			logger.Error().Msgf("can't create receipt for %q: %v", elements[0].Mid, err)
+		} else {
+			logger.Info().Msgf("user with uid = %d issued receipt %q for %d donations", getSession(c).Uid, number, len(elements))
+
+			response = getReceipts(c)
+		}
+	}
+
+	return response
+}
+
+// returns the first postal address of the elements of a sponsor
+func addressOf(elements []ElementDB) string {
+	for _, element := range elements {
+		if element.Address != nil && strings.TrimSpace(*element.Address) != "" {
+			return strings.TrimSpace(*element.Address)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// reserves an element for a sponsor and records its payment
+func payElement(t *testing.T, mid, mail string, amount int64, paid string) {
+	confirmation := strings.Repeat("0", 28) + mid[len(mid)-4:]
+
+	if err := store.ClaimElement(ElementDBNoReservation{Mid: mid, Name: "Erika Mustermann", Mail: &mail}, confirmation, time.Hour, time.Hour); err != nil {
+		t.Fatalf("can't claim %q: %v", mid, err)
+	} else if ok, err := store.ConfirmReservation(mid, confirmation); err != nil || !ok {
+		t.Fatalf("can't confirm reservation of %q: %v", mid, err)
+	} else if ok, err := store.RecordPayment(mid, amount, paid, paymentTransfer, nil); err != nil || !ok {
+		t.Fatalf("can't record payment of %q: %v", mid, err)
+	}
+}
+
+func TestCollectiveReceiptAfterConfirmation(t *testing.T) {
+	setupTestStore(t)
+
+	address := "Erika Mustermann\nMusterstraße 1\n12345 Musterstadt"
+
+	payElement(t, "pv-a1", "Erika@example.org", 125000, "2024-03-01")
+	payElement(t, "pv-a2", "erika@example.org", 55000, "2024-05-01")
+	payElement(t, "pv-a3", "erika@example.org", 55000, "2023-12-31")
+	payElement(t, "pv-b1", "max@example.org", 55000, "2024-03-01")
+
+	// the confirmation of the sponsorship removes the mail-address
+	if ok, err := store.ConfirmElement("pv-a1"); err != nil || !ok {
+		t.Fatalf("can't confirm sponsorship: %v", err)
+	} else if err := store.SetAddress("pv-a1", &address); err != nil {
+		t.Fatalf("can't set address: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/receipts", func(c *fiber.Ctx) error { return postReceipts(c).send(c) })
+
+	request := httptest.NewRequest(fiber.MethodPost, "/receipts", strings.NewReader(`{"mail": " Erika@Example.org", "year": 2024}`))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	if response, err := app.Test(request, -1); err != nil {
+		t.Fatalf("can't issue collective receipt: %v", err)
+	} else if response.StatusCode != fiber.StatusOK {
+		t.Fatalf("collective receipt failed with status %d", response.StatusCode)
+	}
+
+	if receipts, err := store.GetReceipts(); err != nil {
+		t.Fatalf("can't get receipts: %v", err)
+	} else if len(receipts) != 1 {
+		t.Fatalf("%d receipts were issued, expected 1", len(receipts))
+	} else if receipts[0].Kind != receiptCollective || receipts[0].Mids != "pv-a1,pv-a2" || receipts[0].Amount != 180000 {
+		t.Errorf("receipt covers %q with %d cents, expected the donations of 2024 of the sponsor", receipts[0].Mids, receipts[0].Amount)
+	} else if receipts[0].Address != address {
+		t.Errorf("receipt has the address %q, expected %q", receipts[0].Address, address)
+	}
+}
+
+func TestGermanAmountWords(t *testing.T) {
+	tests := map[int64]string{
+		0:         "null Euro",
+		1:         "null Euro und ein Cent",
+		100:       "ein Euro",
+		1250:      "zwölf Euro und fünfzig Cent",
+		2199:      "einundzwanzig Euro und neunundneunzig Cent",
+		10100:     "einhundertein Euro",
+		55000:     "fünfhundertfünfzig Euro",
+		125000:    "eintausendzweihundertfünfzig Euro",
+		111111:    "eintausendeinhundertelf Euro und elf Cent",
+		100000000: "eine Million Euro",
+		210000000: "zwei Millionen einhunderttausend Euro",
+	}
+
+	for cents, words := range tests {
+		if result := germanAmountWords(cents); result != words {
+			t.Errorf("germanAmountWords(%d) = %q, expected %q", cents, result, words)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// fills a svg-template with the template-data and exports it, the format is taken from the extension of the output-file
+type CertificateRenderer interface {
+	export(tpl *template.Template, data SponsorshipTemplateData, outputFile string) error
+}
+
+// renderer for the certificates
+var renderer CertificateRenderer
+
+// creates the renderer selected in the config
+func newRenderer() (CertificateRenderer, error) {
+	if config.Certificate.Archive == "" {
+		return nil, fmt.Errorf(`"certificate.archive" is required`)
+	} else if err := os.MkdirAll(config.Certificate.Archive, 0o750); err != nil {
+		return nil, err
+	}
+
+	switch config.Certificate.Renderer {
+	case "native":
+		return newNativeRenderer()
+	case "inkscape":
+		return newInkscapeRenderer()
+	default:
+		return nil, fmt.Errorf("unknown certificate-renderer %q", config.Certificate.Renderer)
+	}
+}
+
+// renders the certificate-pdf from the active template of the element-type
+func exportCertificate(data SponsorshipTemplateData, pdfFile string) error {
+	if tpl, err := certificateTemplate(data.Type, data.Name != ""); err != nil {
+		return err
+	} else {
+		return renderer.export(tpl, data, pdfFile)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"text/template"
+)
+
+// renders the svg-templates with inkscape
+type inkscapeRenderer struct {
+	executable string
+}
+
+func newInkscapeRenderer() (*inkscapeRenderer, error) {
+	if _, err := os.Stat(config.Certificate.Inkscape); err != nil {
+		return nil, fmt.Errorf("can't find inkscape: %v", err)
+	}
+
+	return &inkscapeRenderer{executable: config.Certificate.Inkscape}, nil
+}
+
+// fills the svg-template with the data and exports it with inkscape, the format is taken from the extension of the output-file
+func (r *inkscapeRenderer) export(tpl *template.Template, data SponsorshipTemplateData, outputFile string) error {
+	// create the temporary svg-file next to the output, in the working-directory of the job
+	if svgFile, err := os.CreateTemp(path.Dir(outputFile), "certificate.*.svg"); err != nil {
+		return err
+	} else {
+		defer os.Remove(svgFile.Name())
+		defer svgFile.Close()
+
+		if err := tpl.Execute(svgFile, data); err != nil {
+			return err
+		} else {
+			actionString := fmt.Sprintf(`--actions=export-filename:%s; export-area-page; export-do`, outputFile)
+
+			// create the pdf or png from the svg-file
+			command := exec.Command(r.executable, actionString, svgFile.Name())
+
+			if output, err := command.CombinedOutput(); err != nil {
+				return fmt.Errorf("inkscape failed: %v: %s", err, output)
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// name of the default-font registered in the pdfs
+const nativeFontFamily = "certificate"
+
+// renders the svg-templates with go-pdf without external programs
+type nativeRenderer struct {
+	// the first font is the default-font for texts with an unknown font-family
+	fonts []svgFont
+}
+
+func newNativeRenderer() (*nativeRenderer, error) {
+	var r nativeRenderer
+
+	// load the fonts once
+	if config.Certificate.Font != "" {
+		if data, err := os.ReadFile(config.Certificate.Font); err != nil {
+			return nil, fmt.Errorf("can't load certificate-font: %v", err)
+		} else {
+			r.fonts = append(r.fonts, svgFont{data: data})
+		}
+	}
+
+	for _, font := range config.Certificate.Fonts {
+		if font.Family == "" {
+			return nil, fmt.Errorf("certificate-font %q has no family", font.File)
+		} else if data, err := os.ReadFile(font.File); err != nil {
+			return nil, fmt.Errorf("can't load certificate-font: %v", err)
+		} else {
+			r.fonts = append(r.fonts, svgFont{family: font.Family, bold: font.Bold, italic: font.Italic, data: data})
+		}
+	}
+
+	return &r, nil
+}
+
+// fills the svg-template with the data and draws it into a pdf, other formats aren't supported
+func (r *nativeRenderer) export(tpl *template.Template, data SponsorshipTemplateData, outputFile string) error {
+	if extension := strings.ToLower(path.Ext(outputFile)); extension != ".pdf" {
+		return fmt.Errorf("the native renderer can't export %q-files", extension)
+	}
+
+	var buf bytes.Buffer
+
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	} else if root, err := parseSVG(&buf); err != nil {
+		return fmt.Errorf("can't parse certificate-template: %v", err)
+	} else if pdf, err := renderSVG(root, r.fonts); err != nil {
+		return fmt.Errorf("can't render certificate-template: %v", err)
+	} else {
+		return pdf.OutputFileAndClose(outputFile)
+	}
+}
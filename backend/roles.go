@@ -0,0 +1,166 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// permission required to access an endpoint
+type Permission int
+
+const (
+	// accessible without login
+	permissionPublic Permission = iota
+	// accessible for every logged-in user
+	permissionAccount
+	// view reservations and sponsorships
+	permissionReadElements
+	// edit names of reservations and sponsorships
+	permissionEditElements
+	// confirm and remove reservations
+	permissionConfirmReservations
+	// remove sponsorships
+	permissionDeleteSponsorships
+	// manage the users and their roles
+	permissionManageUsers
+	// inspect and retry the mail-outbox
+	permissionManageOutbox
+	// record and correct payments
+	permissionManagePayments
+	// export all certificates at once
+	permissionExportCertificates
+	// upload, activate and roll back the certificate-templates
+	permissionManageTemplates
+)
+
+// available user-roles
+const (
+	roleAdmin     = "admin"
+	roleTreasurer = "treasurer"
+	roleEditor    = "editor"
+	roleViewer    = "viewer"
+)
+
+// permissions granted to the individual roles
+var rolePermissions = map[string][]Permission{
+	roleViewer: {
+		permissionReadElements,
+	},
+	roleEditor: {
+		permissionReadElements,
+		permissionEditElements,
+	},
+	roleTreasurer: {
+		permissionReadElements,
+		permissionEditElements,
+		permissionConfirmReservations,
+		permissionManagePayments,
+	},
+	roleAdmin: {
+		permissionReadElements,
+		permissionEditElements,
+		permissionConfirmReservations,
+		permissionDeleteSponsorships,
+		permissionManageUsers,
+		permissionManageOutbox,
+		permissionManagePayments,
+		permissionExportCertificates,
+		permissionManageTemplates,
+	},
+}
+
+// checks wether a role is known
+func validateRole(role string) bool {
+	_, ok := rolePermissions[role]
+
+	return ok
+}
+
+// checks wether a role grants a permission
+func (permission Permission) grantedTo(role string) bool {
+	switch permission {
+	case permissionPublic:
+		return true
+	case permissionAccount:
+		return validateRole(role)
+	default:
+		return slices.Contains(rolePermissions[role], permission)
+	}
+}
+
+// endpoint-handler with the permission required to call it
+type Endpoint struct {
+	handler    func(*fiber.Ctx) responseMessage
+	permission Permission
+}
+
+// checks wether the request is allowed to access an endpoint with the given permission
+// and stores the session of the user in the request-context
+func authorize(c *fiber.Ctx, permission Permission) responseMessage {
+	var response responseMessage
+
+	if permission == permissionPublic {
+		return response
+	}
+
+	if session, err := checkUser(c); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't check user: %v", err)
+	} else if session == nil {
+		response.Status = fiber.StatusUnauthorized
+
+		logger.Info().Msgf("unauthorized request: %q", c.OriginalURL())
+	} else if !permission.grantedTo(session.Role) {
+		response.Status = fiber.StatusForbidden
+
+		logger.Info().Msgf("user with uid = %d and role = %q isn't allowed to access %q", session.Uid, session.Role, c.OriginalURL())
+	} else if session.Token != "" && !scopesGrant(session.Scopes, permission) {
+		response.Status = fiber.StatusForbidden
+		response.Message = "api-token doesn't include the required scope"
+
+		logger.Info().Msgf("api-token %q of user with uid = %d isn't allowed to access %q", session.Token, session.Uid, c.OriginalURL())
+	} else if totpMissing, err := checkTotpMissing(session.Uid, permission); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't check totp of user with uid = %d: %v", session.Uid, err)
+	} else if totpMissing {
+		response.Status = fiber.StatusForbidden
+		response.Message = "totp has to be set up"
+
+		logger.Info().Msgf("user with uid = %d has to set up totp before accessing %q", session.Uid, c.OriginalURL())
+	} else {
+		c.Locals("session", *session)
+	}
+
+	return response
+}
+
+// checks wether a user has to set up totp before accessing an endpoint with the given permission
+func checkTotpMissing(uid int, permission Permission) (bool, error) {
+	// the account-endpoints are needed to set up totp
+	if !config.TOTP.Required || permission == permissionAccount {
+		return false, nil
+	}
+
+	if user, err := store.GetUser(uid); err != nil || user == nil {
+		return false, err
+	} else {
+		return totpMissing(user), nil
+	}
+}
+
+// returns the session of an authorized request
+func getSession(c *fiber.Ctx) JWTPayload {
+	session, _ := c.Locals("session").(JWTPayload)
+
+	return session
+}
+
+// checks wether the session of an already authorized request grants an additional permission
+func sessionGrants(c *fiber.Ctx, permission Permission) bool {
+	session := getSession(c)
+
+	return permission.grantedTo(session.Role) && (session.Token == "" || scopesGrant(session.Scopes, permission))
+}
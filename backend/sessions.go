@@ -0,0 +1,180 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// minimum time between two updates of the last use of a session
+const sessionTouchInterval = time.Minute
+
+// session-entry in the database
+type SessionDB struct {
+	Jti     string `json:"jti"`
+	Uid     int    `json:"uid"`
+	Created int64  `json:"created"`
+	Used    int64  `json:"used"`
+	Ip      string `json:"ip"`
+	Agent   string `json:"agent"`
+}
+
+// session-data sent to the client
+type SessionData struct {
+	SessionDB
+	// name of the user, only for admins
+	Name string `json:"name,omitempty"`
+	// the session belongs to the request
+	Current bool `json:"current"`
+}
+
+// returns the sessions of a user, of all users if uid is 0
+func listSessions(c *fiber.Ctx, uid int) responseMessage {
+	var response responseMessage
+
+	if sessions, err := store.GetSessions(uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get sessions from database: %v", err)
+	} else {
+		current := getSession(c).Jti
+		expired := time.Now().Add(-config.SessionExpire).Unix()
+
+		// add the user-names, if the sessions of other users are included
+		names := map[int]string{}
+
+		if uid != getSession(c).Uid {
+			if users, err := store.GetUsers(); err != nil {
+				response.Status = fiber.StatusInternalServerError
+
+				logger.Error().Msgf("can't get users from database: %v", err)
+
+				return response
+			} else {
+				for _, user := range users {
+					names[user.Uid] = user.Name
+				}
+			}
+		}
+
+		data := []SessionData{}
+
+		for _, session := range sessions {
+			// skip sessions with an expired JWT
+			if session.Created >= expired {
+				data = append(data, SessionData{
+					SessionDB: session,
+					Name:      names[session.Uid],
+					Current:   session.Jti == current,
+				})
+			}
+		}
+
+		response.Data = data
+	}
+
+	return response
+}
+
+// handles get-requests for the sessions of the user
+func getUserSessions(c *fiber.Ctx) responseMessage {
+	return listSessions(c, getSession(c).Uid)
+}
+
+// handles delete-requests to revoke one or all sessions of the user
+func deleteUserSessions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	uid := getSession(c).Uid
+
+	if jti := c.Query("jti"); jti == "" {
+		// revoke all sessions, including the current one
+		if err := store.DeleteSessions(uid); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't remove sessions of user with uid = %d: %v", uid, err)
+		} else {
+			removeSessionCookie(c)
+
+			response.Status = fiber.StatusOK
+
+			logger.Info().Msgf("user with uid = %d revoked all its sessions", uid)
+		}
+	} else if ok, err := store.DeleteSession(jti, uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't remove session of user with uid = %d: %v", uid, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "session doesn't exist"
+
+		logger.Info().Msgf("user with uid = %d tried to revoke unknown session", uid)
+	} else {
+		logger.Info().Msgf("user with uid = %d revoked a session", uid)
+
+		if jti == getSession(c).Jti {
+			removeSessionCookie(c)
+
+			response.Status = fiber.StatusOK
+		} else {
+			response = getUserSessions(c)
+		}
+	}
+
+	return response
+}
+
+// handles get-requests for the sessions of a user or of all users
+func getSessions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if uid := c.QueryInt("uid", 0); uid < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid uid"
+
+		logger.Info().Msg("query doesn't include valid uid")
+	} else {
+		response = listSessions(c, uid)
+	}
+
+	return response
+}
+
+// handles delete-requests to revoke a session or all sessions of a user
+func deleteSessions(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	uid := c.QueryInt("uid", 0)
+
+	if jti := c.Query("jti"); jti != "" {
+		if ok, err := store.DeleteSession(jti, 0); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't remove session: %v", err)
+		} else if !ok {
+			response.Status = fiber.StatusNotFound
+			response.Message = "session doesn't exist"
+
+			logger.Info().Msg("can't revoke unknown session")
+		} else {
+			logger.Info().Msgf("user with uid = %d revoked a session", getSession(c).Uid)
+
+			response = listSessions(c, uid)
+		}
+	} else if uid <= 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid jti or uid"
+
+		logger.Info().Msg("query doesn't include valid jti or uid")
+	} else if err := store.DeleteSessions(uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't remove sessions of user with uid = %d: %v", uid, err)
+	} else {
+		logger.Info().Msgf("user with uid = %d revoked all sessions of user with uid = %d", getSession(c).Uid, uid)
+
+		response = listSessions(c, uid)
+	}
+
+	return response
+}
@@ -0,0 +1,1194 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// storage-backend for elements, users and sessions
+type Store interface {
+	// returns all elements
+	GetElements() ([]ElementDB, error)
+	// returns a single element or nil if it doesn't exist
+	GetElement(mid string) (*ElementDB, error)
+	// returns all reservations confirmed by the sponsor but not yet by an admin
+	GetReservations() ([]ElementDB, error)
+	// returns all confirmed sponsorships
+	GetSponsorships() ([]ElementDBNoReservation, error)
+	// atomically claims an element for a pending reservation, returns errElementUnavailable if it is already taken
+	ClaimElement(element ElementDBNoReservation, confirmation string, expiration, confirmationExpiration time.Duration) error
+	// turns a pending reservation into a normal one, returns false if there is no matching pending reservation
+	ConfirmReservation(mid, confirmation string) (bool, error)
+	// removes an element, if it is still reserved
+	ReleaseElement(mid string) error
+	// turns the confirmed reservation of an element into a sponsorship, returns false if there is no such reservation
+	ConfirmElement(mid string) (bool, error)
+	// turns a sponsorship back into a reservation of the e-mail-address, if its confirmation failed
+	UnconfirmElement(mid, mail string) error
+	// changes the sponsor-name of an element and revokes its certificates with a different name
+	RenameElement(mid, name string) error
+	// changes the postal address of the sponsor of an element, nil to remove it
+	SetAddress(mid string, address *string) error
+	// returns the elements of a sponsor by the e-mail-address, also after the confirmation removed it
+	GetElementsByMail(mail string) ([]ElementDB, error)
+	// removes elements and revokes their certificates
+	DeleteElements(mids ...string) error
+	// adds a payment to the received amount of an element, returns false if there is no confirmed reservation or sponsorship
+	RecordPayment(mid string, amount int64, paid, method string, reference *string) (bool, error)
+	// overwrites the payment-data of an element, returns false if there is no confirmed reservation or sponsorship
+	SetPayment(mid string, payment PaymentDB) (bool, error)
+
+	// returns all users
+	GetUsers() ([]UserDB, error)
+	// returns a single user or nil if it doesn't exist
+	GetUser(uid int) (*UserDB, error)
+	// returns a single user by its name or nil if it doesn't exist
+	GetUserByName(name string) (*UserDB, error)
+	// returns the first user with the e-mail-address or nil if it doesn't exist
+	GetUserByEmail(email string) (*UserDB, error)
+	// adds a new user
+	AddUser(name string, password []byte, role string, email *string) error
+	// changes the password-hash of a user
+	SetPassword(uid int, password []byte) error
+	// changes the role of a user
+	SetRole(uid int, role string) error
+	// changes the e-mail-address of a user, nil to remove it
+	SetEmail(uid int, email *string) error
+	// removes a user
+	DeleteUser(uid int) error
+
+	// stores the secret of a started but not yet verified totp-enrollment, nil to abort it
+	SetTotpEnrollment(uid int, secret *string) error
+	// activates totp for a user and replaces its recovery-codes
+	EnableTotp(uid int, secret string, recoveryCodes []string) error
+	// deactivates totp for a user and removes its recovery-codes
+	DisableTotp(uid int) error
+	// marks a totp-time-step as used, returns false if it or a later one was already used
+	UseTotpStep(uid int, step int64) (bool, error)
+	// replaces the recovery-codes of a user
+	SetRecoveryCodes(uid int, recoveryCodes []string) error
+	// removes a recovery-code, returns false if it doesn't exist
+	UseRecoveryCode(uid int, recoveryCode string) (bool, error)
+	// returns the number of remaining recovery-codes of a user
+	CountRecoveryCodes(uid int) (int, error)
+
+	// returns the current session-token-id of a user
+	GetTokenId(uid int) (int, error)
+	// increases the session-token-id of a user and removes its sessions to invalidate them
+	IncTokenId(uid int) error
+
+	// stores a new session
+	AddSession(session SessionDB) error
+	// returns a session or nil if it doesn't exist
+	GetSession(jti string) (*SessionDB, error)
+	// returns the sessions of a user, of all users if uid is 0
+	GetSessions(uid int) ([]SessionDB, error)
+	// updates the last use and ip-address of a session, at most once per interval
+	TouchSession(jti, ip string, now time.Time, interval time.Duration) error
+	// removes a session, restricted to a user if uid isn't 0, returns false if it doesn't exist
+	DeleteSession(jti string, uid int) (bool, error)
+	// removes all sessions of a user
+	DeleteSessions(uid int) error
+	// removes the sessions created before a point in time
+	PurgeSessions(before time.Time) error
+
+	// stores a new api-token
+	AddAPIToken(token APITokenDB) error
+	// returns the api-token with the given hash or nil if it doesn't exist
+	GetAPIToken(hash string) (*APITokenDB, error)
+	// returns the api-tokens of a user
+	GetAPITokens(uid int) ([]APITokenDB, error)
+	// updates the last use and ip-address of an api-token, at most once per interval
+	TouchAPIToken(id, ip string, now time.Time, interval time.Duration) error
+	// removes an api-token of a user, returns false if it doesn't exist
+	DeleteAPIToken(id string, uid int) (bool, error)
+
+	// returns the login-failures of an account or ip-address, nil if there are none
+	GetLoginThrottle(kind, subject string) (*LoginThrottleDB, error)
+	// counts a failed login, restarting the count if the last failure is older than reset, and locks the subject once threshold is reached
+	RecordLoginFailure(kind, subject string, now time.Time, reset time.Duration, threshold int, lockout time.Duration) (LoginThrottleDB, error)
+	// removes the login-failures and the lock of an account or ip-address, returns false if there were none
+	ClearLoginFailures(kind, subject string) (bool, error)
+	// returns the accounts and ip-addresses that are locked at the given time
+	GetLockouts(now time.Time) ([]LoginThrottleDB, error)
+
+	// adds a mail to the outbox
+	EnqueueMail(mail OutboxEntryDB) error
+	// returns the mails in the outbox with the given status, all if status is empty
+	GetMails(status string) ([]OutboxDB, error)
+	// returns the queued mails that are due for delivery at the given unix-time
+	GetDueMails(now int64) ([]OutboxMessageDB, error)
+	// updates the delivery-state of a mail, returns false if it doesn't exist
+	UpdateMail(id int, state OutboxStateDB) (bool, error)
+	// removes a mail from the outbox, returns false if it doesn't exist
+	DeleteMail(id int) (bool, error)
+
+	// adds an imported bank-transaction, returns false if it was already imported
+	AddTransaction(transaction TransactionEntryDB) (bool, error)
+	// returns the imported bank-transactions with the given status, all if status is empty
+	GetTransactions(status string) ([]TransactionDB, error)
+	// returns a single bank-transaction or nil if it doesn't exist
+	GetTransaction(id int) (*TransactionDB, error)
+	// changes the status of a bank-transaction from the expected one and sets the matched element if mid isn't nil,
+	// returns false if the transaction doesn't have the expected status
+	UpdateTransaction(id int, from, to string, mid *string) (bool, error)
+	// reopens an approved bank-transaction and restores the originally proposed element, which may be nil
+	ReopenTransaction(id int, mid *string) (bool, error)
+
+	// adds a donation-receipt with the next number of its year, which is passed to render for creating the pdf
+	AddReceipt(receipt ReceiptEntryDB, render func(number string) ([]byte, error)) (string, error)
+	// returns all donation-receipts without their pdf
+	GetReceipts() ([]ReceiptDB, error)
+	// returns a single donation-receipt with its pdf or nil if it doesn't exist
+	GetReceiptFile(id int) (*ReceiptFileDB, error)
+
+	// returns the valid certificate of an element if it carries the same name,
+	// otherwise revokes it and stores a new certificate with the given serial, at most one certificate per element is valid
+	IssueCertificate(mid, name, serial string, issued int64) (CertificateDB, error)
+	// stores the hash of the archived pdf of a certificate
+	SetCertificateHash(id int, hash string) error
+	// returns all certificates that aren't revoked
+	GetValidCertificates() ([]CertificateDB, error)
+	// returns a single certificate by its serial or nil if it doesn't exist
+	GetCertificate(serial string) (*CertificateDB, error)
+	// revokes the valid certificates of an element, returns false if there are none
+	RevokeCertificates(mid string, revoked int64) (bool, error)
+
+	// adds a certificate-template with the next version of its element-type and variant, returns the version
+	AddCertificateTemplate(tpl CertificateTemplateEntryDB) (int, error)
+	// returns all certificate-templates without their content
+	GetCertificateTemplates() ([]CertificateTemplateDB, error)
+	// returns a single certificate-template with its content or nil if it doesn't exist
+	GetCertificateTemplate(id int) (*CertificateTemplateFileDB, error)
+	// returns the active certificate-template of an element-type and variant or nil if there is none
+	GetActiveCertificateTemplate(elementType, variant string) (*CertificateTemplateFileDB, error)
+	// activates a certificate-template and deactivates the other versions, returns false if it doesn't exist
+	ActivateCertificateTemplate(id int) (bool, error)
+	// activates the version before the active one, or none if the first one is active, returns false if none is active
+	RollbackCertificateTemplate(elementType, variant string) (bool, error)
+
+	// returns the version of the newest applied schema-migration
+	SchemaVersion() (int, error)
+
+	Close() error
+}
+
+// error for claiming an element that is already taken or reserved
+var errElementUnavailable = errors.New("element is not available")
+
+// storage-backend
+var store Store
+
+// opens the store selected in the config
+func openStore() (Store, error) {
+	switch config.Database.Driver {
+	case "mysql":
+		return openMySQLStore()
+	case "sqlite":
+		return openSQLiteStore(config.Database.File)
+	default:
+		return nil, fmt.Errorf("unknown database-driver %q", config.Database.Driver)
+	}
+}
+
+// store-implementation on top of database/sql
+type sqlStore struct {
+	db *sql.DB
+
+	// checks wether an error is caused by a duplicate primary key
+	isDuplicateEntry func(err error) bool
+	// sql-condition for an expired reservation with the expiration and the confirmation-expiration in seconds as parameters
+	expiredCondition string
+}
+
+func (s *sqlStore) GetElements() ([]ElementDB, error) {
+	return dbSelect[ElementDB](s.db, "elements", "*")
+}
+
+func (s *sqlStore) GetElement(mid string) (*ElementDB, error) {
+	if res, err := dbSelect[ElementDB](s.db, "elements", "mid = ? LIMIT 1", mid); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetReservations() ([]ElementDB, error) {
+	return dbSelect[ElementDB](s.db, "elements", "reservation IS NOT NULL AND confirmation IS NULL")
+}
+
+func (s *sqlStore) GetSponsorships() ([]ElementDBNoReservation, error) {
+	return dbSelect[ElementDBNoReservation](s.db, "elements", "reservation IS NULL")
+}
+
+func (s *sqlStore) ClaimElement(element ElementDBNoReservation, confirmation string, expiration, confirmationExpiration time.Duration) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// remove an expired reservation of the element, so it can be claimed again
+	if _, err := tx.Exec("DELETE FROM elements WHERE mid = ? AND "+s.expiredCondition, element.Mid, int(expiration.Seconds()), int(confirmationExpiration.Seconds())); err != nil {
+		return err
+	}
+
+	// keep a pseudonymous id of the sponsor, since the mail-address is removed with the confirmation
+	var sponsor *string
+
+	if element.Mail != nil {
+		id := sponsorID(*element.Mail)
+		sponsor = &id
+	}
+
+	// the mid is the primary key, so the insert fails if the element is already in the table
+	if _, err := tx.Exec("INSERT INTO elements (mid, name, mail, sponsor, confirmation, due, remittance, address) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", element.Mid, element.Name, element.Mail, sponsor, confirmation, element.Due, element.Remittance, element.Address); err != nil {
+		if s.isDuplicateEntry(err) {
+			return errElementUnavailable
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) ConfirmReservation(mid, confirmation string) (bool, error) {
+	// clearing the confirmation makes the link single-use, the reservation starts anew
+	if res, err := s.db.Exec("UPDATE elements SET confirmation = NULL, reservation = CURRENT_TIMESTAMP WHERE mid = ? AND confirmation = ?", mid, confirmation); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) ReleaseElement(mid string) error {
+	_, err := s.db.Exec("DELETE FROM elements WHERE mid = ? AND reservation IS NOT NULL", mid)
+
+	return err
+}
+
+func (s *sqlStore) ConfirmElement(mid string) (bool, error) {
+	// the condition lets only one of several concurrent confirmations succeed
+	if res, err := s.db.Exec("UPDATE elements SET reservation = NULL, mail = NULL WHERE mid = ? AND reservation IS NOT NULL AND confirmation IS NULL", mid); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) UnconfirmElement(mid, mail string) error {
+	_, err := s.db.Exec("UPDATE elements SET reservation = CURRENT_TIMESTAMP, mail = ? WHERE mid = ? AND reservation IS NULL", mail, mid)
+
+	return err
+}
+
+func (s *sqlStore) RenameElement(mid, name string) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE elements SET name = ? WHERE mid = ?", name, mid); err != nil {
+		return err
+	} else if _, err := tx.Exec("UPDATE certificates SET revoked = ? WHERE mid = ? AND name <> ? AND revoked IS NULL", time.Now().Unix(), mid, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) SetAddress(mid string, address *string) error {
+	return dbUpdate(s.db, "elements", struct{ Address *string }{Address: address}, struct{ Mid string }{Mid: mid})
+}
+
+func (s *sqlStore) GetElementsByMail(mail string) ([]ElementDB, error) {
+	// elements reserved before the sponsor-id was introduced can only be found by their mail-address
+	return dbSelect[ElementDB](s.db, "elements", "(sponsor = ? OR mail = ?) ORDER BY mid", sponsorID(mail), mail)
+}
+
+func (s *sqlStore) DeleteElements(mids ...string) error {
+	if len(mids) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(mids))
+	for ii, mid := range mids {
+		args[ii] = mid
+	}
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	placeholders := strings.Repeat("?, ", len(mids)-1) + "?"
+
+	if _, err := tx.Exec(fmt.Sprintf("DELETE FROM elements WHERE mid IN (%s)", placeholders), args...); err != nil {
+		return err
+	} else if _, err := tx.Exec(fmt.Sprintf("UPDATE certificates SET revoked = ? WHERE mid IN (%s) AND revoked IS NULL", placeholders), append([]any{time.Now().Unix()}, args...)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) RecordPayment(mid string, amount int64, paid, method string, reference *string) (bool, error) {
+	if res, err := s.db.Exec("UPDATE elements SET received = received + ?, paid = ?, method = ?, reference = ? WHERE mid = ? AND confirmation IS NULL", amount, paid, method, reference, mid); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) SetPayment(mid string, payment PaymentDB) (bool, error) {
+	if res, err := s.db.Exec("UPDATE elements SET due = ?, received = ?, paid = ?, method = ?, reference = ? WHERE mid = ? AND confirmation IS NULL", payment.Due, payment.Received, payment.Paid, payment.Method, payment.Reference, mid); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) GetUsers() ([]UserDB, error) {
+	return dbSelect[UserDB](s.db, "users", "")
+}
+
+func (s *sqlStore) GetUser(uid int) (*UserDB, error) {
+	if res, err := dbSelect[UserDB](s.db, "users", "uid = ? LIMIT 1", uid); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetUserByName(name string) (*UserDB, error) {
+	if res, err := dbSelect[UserDB](s.db, "users", "name = ? LIMIT 1", name); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetUserByEmail(email string) (*UserDB, error) {
+	if res, err := dbSelect[UserDB](s.db, "users", "email = ? ORDER BY uid LIMIT 1", email); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) AddUser(name string, password []byte, role string, email *string) error {
+	return dbInsert(s.db, "users", struct {
+		Name     string
+		Password []byte
+		Role     string
+		Email    *string
+	}{Name: name, Password: password, Role: role, Email: email})
+}
+
+func (s *sqlStore) SetPassword(uid int, password []byte) error {
+	return dbUpdate(s.db, "users", struct{ Password []byte }{Password: password}, struct{ Uid int }{Uid: uid})
+}
+
+func (s *sqlStore) SetRole(uid int, role string) error {
+	return dbUpdate(s.db, "users", struct{ Role string }{Role: role}, struct{ Uid int }{Uid: uid})
+}
+
+func (s *sqlStore) SetEmail(uid int, email *string) error {
+	return dbUpdate(s.db, "users", struct{ Email *string }{Email: email}, struct{ Uid int }{Uid: uid})
+}
+
+func (s *sqlStore) DeleteUser(uid int) error {
+	return dbDelete(s.db, "users", struct{ Uid int }{Uid: uid})
+}
+
+func (s *sqlStore) SetTotpEnrollment(uid int, secret *string) error {
+	return dbUpdate(s.db, "users", struct{ Enrollment *string }{Enrollment: secret}, struct{ Uid int }{Uid: uid})
+}
+
+func (s *sqlStore) EnableTotp(uid int, secret string, recoveryCodes []string) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp = ?, enrollment = NULL, step = 0 WHERE uid = ?", secret, uid); err != nil {
+		return err
+	} else if err := replaceRecoveryCodes(tx, uid, recoveryCodes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) DisableTotp(uid int) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp = NULL, enrollment = NULL, step = 0 WHERE uid = ?", uid); err != nil {
+		return err
+	} else if err := replaceRecoveryCodes(tx, uid, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) UseTotpStep(uid int, step int64) (bool, error) {
+	// only update the step if it is newer, so every code can be used only once
+	if res, err := s.db.Exec("UPDATE users SET step = ? WHERE uid = ? AND step < ?", step, uid, step); err != nil {
+		return false, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return rows == 1, nil
+	}
+}
+
+func (s *sqlStore) SetRecoveryCodes(uid int, recoveryCodes []string) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, uid, recoveryCodes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// replaces the recovery-codes of a user inside of a transaction
+func replaceRecoveryCodes(tx *sql.Tx, uid int, recoveryCodes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE uid = ?", uid); err != nil {
+		return err
+	}
+
+	for _, code := range recoveryCodes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (uid, code) VALUES (?, ?)", uid, code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sqlStore) UseRecoveryCode(uid int, recoveryCode string) (bool, error) {
+	if res, err := s.db.Exec("DELETE FROM recovery_codes WHERE uid = ? AND code = ?", uid, recoveryCode); err != nil {
+		return false, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return rows == 1, nil
+	}
+}
+
+func (s *sqlStore) CountRecoveryCodes(uid int) (int, error) {
+	var count int
+
+	err := s.db.QueryRow("SELECT COUNT(*) FROM recovery_codes WHERE uid = ?", uid).Scan(&count)
+
+	return count, err
+}
+
+func (s *sqlStore) GetTokenId(uid int) (int, error) {
+	if user, err := s.GetUser(uid); err != nil {
+		return -1, err
+	} else if user == nil {
+		return -1, fmt.Errorf("can't get user with uid = %q from database", uid)
+	} else {
+		return user.Tid, nil
+	}
+}
+
+func (s *sqlStore) IncTokenId(uid int) error {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET tid = tid + 1 WHERE uid = ?", uid); err != nil {
+		return err
+	} else if _, err := tx.Exec("DELETE FROM sessions WHERE uid = ?", uid); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStore) AddSession(session SessionDB) error {
+	return dbInsert(s.db, "sessions", session)
+}
+
+func (s *sqlStore) GetSession(jti string) (*SessionDB, error) {
+	if res, err := dbSelect[SessionDB](s.db, "sessions", "jti = ? LIMIT 1", jti); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetSessions(uid int) ([]SessionDB, error) {
+	if uid == 0 {
+		return dbSelect[SessionDB](s.db, "sessions", "1 = 1 ORDER BY used DESC")
+	} else {
+		return dbSelect[SessionDB](s.db, "sessions", "uid = ? ORDER BY used DESC", uid)
+	}
+}
+
+func (s *sqlStore) TouchSession(jti, ip string, now time.Time, interval time.Duration) error {
+	_, err := s.db.Exec("UPDATE sessions SET used = ?, ip = ? WHERE jti = ? AND (used < ? OR ip <> ?)", now.Unix(), ip, jti, now.Add(-interval).Unix(), ip)
+
+	return err
+}
+
+func (s *sqlStore) DeleteSession(jti string, uid int) (bool, error) {
+	var res sql.Result
+	var err error
+
+	if uid == 0 {
+		res, err = s.db.Exec("DELETE FROM sessions WHERE jti = ?", jti)
+	} else {
+		res, err = s.db.Exec("DELETE FROM sessions WHERE jti = ? AND uid = ?", jti, uid)
+	}
+
+	if err != nil {
+		return false, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return rows > 0, nil
+	}
+}
+
+func (s *sqlStore) DeleteSessions(uid int) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE uid = ?", uid)
+
+	return err
+}
+
+func (s *sqlStore) PurgeSessions(before time.Time) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE created < ?", before.Unix())
+
+	return err
+}
+
+func (s *sqlStore) AddAPIToken(token APITokenDB) error {
+	return dbInsert(s.db, "api_tokens", token)
+}
+
+func (s *sqlStore) GetAPIToken(hash string) (*APITokenDB, error) {
+	if res, err := dbSelect[APITokenDB](s.db, "api_tokens", "hash = ? LIMIT 1", hash); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetAPITokens(uid int) ([]APITokenDB, error) {
+	return dbSelect[APITokenDB](s.db, "api_tokens", "uid = ? ORDER BY created DESC", uid)
+}
+
+func (s *sqlStore) TouchAPIToken(id, ip string, now time.Time, interval time.Duration) error {
+	_, err := s.db.Exec("UPDATE api_tokens SET used = ?, ip = ? WHERE id = ? AND (used < ? OR ip <> ?)", now.Unix(), ip, id, now.Add(-interval).Unix(), ip)
+
+	return err
+}
+
+func (s *sqlStore) DeleteAPIToken(id string, uid int) (bool, error) {
+	if res, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ? AND uid = ?", id, uid); err != nil {
+		return false, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return rows > 0, nil
+	}
+}
+
+func (s *sqlStore) GetLoginThrottle(kind, subject string) (*LoginThrottleDB, error) {
+	if res, err := dbSelect[LoginThrottleDB](s.db, "login_throttle", "kind = ? AND subject = ? LIMIT 1", kind, subject); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) RecordLoginFailure(kind, subject string, now time.Time, reset time.Duration, threshold int, lockout time.Duration) (LoginThrottleDB, error) {
+	var throttle LoginThrottleDB
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return throttle, err
+	}
+
+	defer tx.Rollback()
+
+	// increases the counter, or restarts it if the last failure is too old
+	increment := func() (sql.Result, error) {
+		return tx.Exec("UPDATE login_throttle SET failures = CASE WHEN last < ? THEN 1 ELSE failures + 1 END, last = ? WHERE kind = ? AND subject = ?", now.Add(-reset).Unix(), now.Unix(), kind, subject)
+	}
+
+	if res, err := increment(); err != nil {
+		return throttle, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return throttle, err
+	} else if rows == 0 {
+		if _, err := tx.Exec("INSERT INTO login_throttle (kind, subject, failures, last) VALUES (?, ?, 1, ?)", kind, subject, now.Unix()); s.isDuplicateEntry(err) {
+			// a concurrent failure created the entry in the meantime
+			if _, err := increment(); err != nil {
+				return throttle, err
+			}
+		} else if err != nil {
+			return throttle, err
+		}
+	}
+
+	if threshold > 0 {
+		if _, err := tx.Exec("UPDATE login_throttle SET locked = ? WHERE kind = ? AND subject = ? AND failures >= ?", now.Add(lockout).Unix(), kind, subject, threshold); err != nil {
+			return throttle, err
+		}
+	}
+
+	if err := tx.QueryRow("SELECT kind, subject, failures, last, locked FROM login_throttle WHERE kind = ? AND subject = ?", kind, subject).Scan(&throttle.Kind, &throttle.Subject, &throttle.Failures, &throttle.Last, &throttle.Locked); err != nil {
+		return throttle, err
+	}
+
+	return throttle, tx.Commit()
+}
+
+func (s *sqlStore) ClearLoginFailures(kind, subject string) (bool, error) {
+	if res, err := s.db.Exec("DELETE FROM login_throttle WHERE kind = ? AND subject = ?", kind, subject); err != nil {
+		return false, err
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return rows > 0, nil
+	}
+}
+
+func (s *sqlStore) GetLockouts(now time.Time) ([]LoginThrottleDB, error) {
+	return dbSelect[LoginThrottleDB](s.db, "login_throttle", "locked > ? ORDER BY locked", now.Unix())
+}
+
+func (s *sqlStore) EnqueueMail(mail OutboxEntryDB) error {
+	return dbInsert(s.db, "outbox", mail)
+}
+
+func (s *sqlStore) GetMails(status string) ([]OutboxDB, error) {
+	if status == "" {
+		return dbSelect[OutboxDB](s.db, "outbox", "1 = 1 ORDER BY id")
+	} else {
+		return dbSelect[OutboxDB](s.db, "outbox", "status = ? ORDER BY id", status)
+	}
+}
+
+func (s *sqlStore) GetDueMails(now int64) ([]OutboxMessageDB, error) {
+	return dbSelect[OutboxMessageDB](s.db, "outbox", "status = ? AND due <= ? ORDER BY due", outboxQueued, now)
+}
+
+func (s *sqlStore) UpdateMail(id int, state OutboxStateDB) (bool, error) {
+	if res, err := s.db.Exec("UPDATE outbox SET status = ?, attempts = ?, due = ?, reason = ? WHERE id = ?", state.Status, state.Attempts, state.Due, state.Reason, id); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) DeleteMail(id int) (bool, error) {
+	if res, err := s.db.Exec("DELETE FROM outbox WHERE id = ?", id); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) AddTransaction(transaction TransactionEntryDB) (bool, error) {
+	if err := dbInsert(s.db, "transactions", transaction); s.isDuplicateEntry(err) {
+		return false, nil
+	} else {
+		return err == nil, err
+	}
+}
+
+func (s *sqlStore) GetTransactions(status string) ([]TransactionDB, error) {
+	if status == "" {
+		return dbSelect[TransactionDB](s.db, "transactions", "1 = 1 ORDER BY booked, id")
+	} else {
+		return dbSelect[TransactionDB](s.db, "transactions", "status = ? ORDER BY booked, id", status)
+	}
+}
+
+func (s *sqlStore) GetTransaction(id int) (*TransactionDB, error) {
+	if res, err := dbSelect[TransactionDB](s.db, "transactions", "id = ? LIMIT 1", id); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) UpdateTransaction(id int, from, to string, mid *string) (bool, error) {
+	if res, err := s.db.Exec("UPDATE transactions SET status = ?, mid = COALESCE(?, mid) WHERE id = ? AND status = ?", to, mid, id, from); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) ReopenTransaction(id int, mid *string) (bool, error) {
+	if res, err := s.db.Exec("UPDATE transactions SET status = ?, mid = ? WHERE id = ? AND status = ?", transactionOpen, mid, id, transactionApproved); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n == 1, nil
+	}
+}
+
+func (s *sqlStore) AddReceipt(receipt ReceiptEntryDB, render func(number string) ([]byte, error)) (string, error) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	// the numbers are sequential within a year, the unique-constraint catches concurrent receipts
+	if err := tx.QueryRow("SELECT COALESCE(MAX(seq), 0) + 1 FROM receipts WHERE year = ?", receipt.Year).Scan(&receipt.Seq); err != nil {
+		return "", err
+	}
+
+	receipt.Number = fmt.Sprintf("%d-%04d", receipt.Year, receipt.Seq)
+
+	if receipt.Pdf, err = render(receipt.Number); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec("INSERT INTO receipts (year, seq, number, kind, mids, name, mail, address, amount, created, pdf) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		receipt.Year, receipt.Seq, receipt.Number, receipt.Kind, receipt.Mids, receipt.Name, receipt.Mail, receipt.Address, receipt.Amount, receipt.Created, receipt.Pdf); err != nil {
+		return "", err
+	}
+
+	return receipt.Number, tx.Commit()
+}
+
+func (s *sqlStore) GetReceipts() ([]ReceiptDB, error) {
+	return dbSelect[ReceiptDB](s.db, "receipts", "1 = 1 ORDER BY year, seq")
+}
+
+func (s *sqlStore) GetReceiptFile(id int) (*ReceiptFileDB, error) {
+	if res, err := dbSelect[ReceiptFileDB](s.db, "receipts", "id = ? LIMIT 1", id); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) IssueCertificate(mid, name, serial string, issued int64) (CertificateDB, error) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return CertificateDB{}, err
+	}
+
+	defer tx.Rollback()
+
+	certificate := CertificateDB{Mid: mid}
+
+	// keep the valid certificate, as long as the name on it is still correct
+	if err := tx.QueryRow("SELECT id, serial, name, issued, hash FROM certificates WHERE mid = ? AND revoked IS NULL", mid).Scan(&certificate.Id, &certificate.Serial, &certificate.Name, &certificate.Issued, &certificate.Hash); err == nil && certificate.Name == name {
+		return certificate, nil
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return CertificateDB{}, err
+	}
+
+	certificate = CertificateDB{Serial: serial, Mid: mid, Name: name, Issued: issued}
+
+	if _, err := tx.Exec("UPDATE certificates SET revoked = ? WHERE mid = ? AND revoked IS NULL", issued, mid); err != nil {
+		return CertificateDB{}, err
+	} else if res, err := tx.Exec("INSERT INTO certificates (serial, mid, name, issued) VALUES (?, ?, ?, ?)", serial, mid, name, issued); err != nil {
+		// only one certificate per element can be valid, so a concurrent request issued one first
+		if s.isDuplicateEntry(err) {
+			tx.Rollback()
+
+			if valid, selectErr := dbSelect[CertificateDB](s.db, "certificates", "mid = ? AND revoked IS NULL", mid); selectErr == nil && len(valid) == 1 && valid[0].Name == name {
+				return valid[0], nil
+			}
+		}
+
+		return CertificateDB{}, err
+	} else if id, err := res.LastInsertId(); err != nil {
+		return CertificateDB{}, err
+	} else {
+		certificate.Id = int(id)
+	}
+
+	return certificate, tx.Commit()
+}
+
+func (s *sqlStore) SetCertificateHash(id int, hash string) error {
+	return dbUpdate(s.db, "certificates", struct{ Hash string }{Hash: hash}, struct{ Id int }{Id: id})
+}
+
+func (s *sqlStore) GetValidCertificates() ([]CertificateDB, error) {
+	return dbSelect[CertificateDB](s.db, "certificates", "revoked IS NULL")
+}
+
+func (s *sqlStore) GetCertificate(serial string) (*CertificateDB, error) {
+	if res, err := dbSelect[CertificateDB](s.db, "certificates", "serial = ? LIMIT 1", serial); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) RevokeCertificates(mid string, revoked int64) (bool, error) {
+	if res, err := s.db.Exec("UPDATE certificates SET revoked = ? WHERE mid = ? AND revoked IS NULL", revoked, mid); err != nil {
+		return false, err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return false, err
+	} else {
+		return n > 0, nil
+	}
+}
+
+func (s *sqlStore) AddCertificateTemplate(tpl CertificateTemplateEntryDB) (int, error) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	// the versions are sequential per element-type and variant, the unique-constraint catches concurrent uploads
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) + 1 FROM certificate_templates WHERE type = ? AND variant = ?", tpl.Type, tpl.Variant).Scan(&tpl.Version); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("INSERT INTO certificate_templates (type, variant, version, content, created) VALUES (?, ?, ?, ?, ?)", tpl.Type, tpl.Variant, tpl.Version, tpl.Content, tpl.Created); err != nil {
+		return 0, err
+	}
+
+	return tpl.Version, tx.Commit()
+}
+
+func (s *sqlStore) GetCertificateTemplates() ([]CertificateTemplateDB, error) {
+	return dbSelect[CertificateTemplateDB](s.db, "certificate_templates", "1 = 1 ORDER BY type, variant, version")
+}
+
+func (s *sqlStore) GetCertificateTemplate(id int) (*CertificateTemplateFileDB, error) {
+	if res, err := dbSelect[CertificateTemplateFileDB](s.db, "certificate_templates", "id = ? LIMIT 1", id); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) GetActiveCertificateTemplate(elementType, variant string) (*CertificateTemplateFileDB, error) {
+	if res, err := dbSelect[CertificateTemplateFileDB](s.db, "certificate_templates", "type = ? AND variant = ? AND active = 1 LIMIT 1", elementType, variant); err != nil || len(res) != 1 {
+		return nil, err
+	} else {
+		return &res[0], nil
+	}
+}
+
+func (s *sqlStore) ActivateCertificateTemplate(id int) (bool, error) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var elementType, variant string
+
+	if err := tx.QueryRow("SELECT type, variant FROM certificate_templates WHERE id = ?", id).Scan(&elementType, &variant); errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	} else if _, err := tx.Exec("UPDATE certificate_templates SET active = 0 WHERE type = ? AND variant = ?", elementType, variant); err != nil {
+		return false, err
+	} else if _, err := tx.Exec("UPDATE certificate_templates SET active = 1 WHERE id = ?", id); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (s *sqlStore) RollbackCertificateTemplate(elementType, variant string) (bool, error) {
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	var version int
+	var previous sql.NullInt64
+
+	// mysql doesn't allow a subquery on the updated table, so the previous version is selected first
+	if err := tx.QueryRow("SELECT version FROM certificate_templates WHERE type = ? AND variant = ? AND active = 1", elementType, variant).Scan(&version); errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	} else if err := tx.QueryRow("SELECT id FROM certificate_templates WHERE type = ? AND variant = ? AND version < ? ORDER BY version DESC LIMIT 1", elementType, variant, version).Scan(&previous); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	} else if _, err := tx.Exec("UPDATE certificate_templates SET active = 0 WHERE type = ? AND variant = ?", elementType, variant); err != nil {
+		return false, err
+	} else if previous.Valid {
+		if _, err := tx.Exec("UPDATE certificate_templates SET active = 1 WHERE id = ?", previous.Int64); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func (s *sqlStore) SchemaVersion() (int, error) {
+	var version int
+
+	err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+
+	return version, err
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// query the database
+func dbSelect[T any](db *sql.DB, table string, where string, args ...any) ([]T, error) {
+	// validate columns against struct T
+	tType := reflect.TypeOf(new(T)).Elem()
+	columns := make([]string, tType.NumField())
+
+	validColumns := make(map[string]any)
+	for ii := 0; ii < tType.NumField(); ii++ {
+		field := tType.Field(ii)
+		validColumns[strings.ToLower(field.Name)] = struct{}{}
+		columns[ii] = strings.ToLower(field.Name)
+	}
+
+	for _, col := range columns {
+		if _, ok := validColumns[strings.ToLower(col)]; !ok {
+			return nil, fmt.Errorf("invalid column: %s for struct type %T", col, new(T))
+		}
+	}
+
+	// create the query
+	completeQuery := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table)
+
+	if where != "" && where != "*" {
+		completeQuery = fmt.Sprintf("%s WHERE %s", completeQuery, where)
+	}
+
+	var rows *sql.Rows
+	var err error
+
+	if len(args) > 0 {
+		db.Ping()
+
+		rows, err = db.Query(completeQuery, args...)
+	} else {
+		db.Ping()
+
+		rows, err = db.Query(completeQuery)
+	}
+
+	if err != nil {
+		logger.Error().Msgf("database access failed with error %v", err)
+
+		return nil, err
+	}
+
+	defer rows.Close()
+	results := []T{}
+
+	title := cases.Title(language.Und)
+
+	for rows.Next() {
+		var lineResult T
+
+		scanArgs := make([]any, len(columns))
+		v := reflect.ValueOf(&lineResult).Elem()
+
+		for ii, col := range columns {
+			colTitle := title.String(col)
+
+			field := v.FieldByName(colTitle)
+
+			if field.IsValid() && field.CanSet() {
+				scanArgs[ii] = field.Addr().Interface()
+			} else {
+				logger.Warn().Msgf("Field %s not found in struct %T", col, lineResult)
+				scanArgs[ii] = new(any) // save dummy value
+			}
+		}
+
+		// scan the row into the struct
+		if err := rows.Scan(scanArgs...); err != nil {
+			logger.Warn().Msgf("Scan-error: %v", err)
+
+			return nil, err
+		}
+
+		results = append(results, lineResult)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error().Msgf("rows-error: %v", err)
+		return nil, err
+	} else {
+		return results, nil
+	}
+}
+
+// insert data intot the databse
+func dbInsert(db *sql.DB, table string, vals any) error {
+	// extract columns from vals
+	v := reflect.ValueOf(vals)
+	t := v.Type()
+
+	columns := make([]string, t.NumField())
+	values := make([]any, t.NumField())
+
+	for ii := 0; ii < t.NumField(); ii++ {
+		fieldValue := v.Field(ii)
+
+		field := t.Field(ii)
+
+		columns[ii] = strings.ToLower(field.Name)
+		values[ii] = fieldValue.Interface()
+	}
+
+	placeholders := strings.Repeat(("?, "), len(columns))
+	placeholders = strings.TrimSuffix(placeholders, ", ")
+
+	completeQuery := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+
+	_, err := db.Exec(completeQuery, values...)
+
+	return err
+}
+
+// update data in the database
+func dbUpdate(db *sql.DB, table string, set, where any) error {
+	setV := reflect.ValueOf(set)
+	setT := setV.Type()
+
+	setColumns := make([]string, setT.NumField())
+	setValues := make([]any, setT.NumField())
+
+	for ii := 0; ii < setT.NumField(); ii++ {
+		fieldValue := setV.Field(ii)
+
+		field := setT.Field(ii)
+
+		setColumns[ii] = strings.ToLower(field.Name) + " = ?"
+		setValues[ii] = fieldValue.Interface()
+	}
+
+	whereV := reflect.ValueOf(where)
+	whereT := whereV.Type()
+
+	whereColumns := make([]string, whereT.NumField())
+	whereValues := make([]any, whereT.NumField())
+
+	for ii := 0; ii < whereT.NumField(); ii++ {
+		fieldValue := whereV.Field(ii)
+
+		// skip empty (zero) values
+		if !fieldValue.IsZero() {
+			field := whereT.Field(ii)
+
+			whereColumns[ii] = strings.ToLower(field.Name) + " = ?"
+			whereValues[ii] = fmt.Sprint(fieldValue.Interface())
+		}
+	}
+
+	sets := strings.Join(setColumns, ", ")
+	wheres := strings.Join(whereColumns, " AND ")
+
+	placeholderValues := append(setValues, whereValues...)
+
+	completeQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, sets, wheres)
+
+	_, err := db.Exec(completeQuery, placeholderValues...)
+
+	return err
+}
+
+// remove data from the database
+func dbDelete(db *sql.DB, table string, vals any) error {
+	// extract columns from vals
+	v := reflect.ValueOf(vals)
+	t := v.Type()
+
+	columns := make([]string, t.NumField())
+	values := make([]any, t.NumField())
+
+	for ii := 0; ii < t.NumField(); ii++ {
+		fieldValue := v.Field(ii)
+
+		// skip empty (zero) values
+		if !fieldValue.IsZero() {
+			field := t.Field(ii)
+
+			columns[ii] = strings.ToLower(field.Name) + " = ?"
+			values[ii] = fmt.Sprint(fieldValue.Interface())
+		}
+	}
+
+	completeQuery := fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(columns, ", "))
+
+	_, err := db.Exec(completeQuery, values...)
+
+	return err
+}
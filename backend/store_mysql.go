@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysql error-number for a duplicate primary key
+const mysqlErrDuplicateEntry = 1062
+
+// opens the mysql-database from the config
+func openMySQLStore() (Store, error) {
+	sqlConfig := mysql.Config{
+		AllowNativePasswords: true,
+		ClientFoundRows:      true,
+		Net:                  "tcp",
+		User:                 config.Database.User,
+		Passwd:               config.Database.Password,
+		Addr:                 config.Database.Host,
+		DBName:               config.Database.Database,
+	}
+
+	db, err := sql.Open("mysql", sqlConfig.FormatDSN())
+
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxIdleConns(10)
+	db.SetMaxIdleConns(100)
+	db.SetConnMaxLifetime(time.Minute)
+
+	return newMySQLStore(db), nil
+}
+
+// creates a store on top of an opened mysql-database
+func newMySQLStore(db *sql.DB) *sqlStore {
+	return &sqlStore{
+		db: db,
+		isDuplicateEntry: func(err error) bool {
+			var mysqlErr *mysql.MySQLError
+
+			return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+		},
+		expiredCondition: "reservation IS NOT NULL AND reservation < NOW() - INTERVAL (CASE WHEN confirmation IS NULL THEN ? ELSE ? END) SECOND",
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// opens the sqlite-database in a single file
+func openSQLiteStore(file string) (Store, error) {
+	if file == "" {
+		return nil, fmt.Errorf(`"database.file" is required for the sqlite-driver`)
+	}
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", file))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newSQLiteStore(db), nil
+}
+
+// creates a store on top of an opened sqlite-database
+func newSQLiteStore(db *sql.DB) *sqlStore {
+	// sqlite allows only a single writer, serialize the access instead of failing with "database is locked"
+	db.SetMaxOpenConns(1)
+
+	return &sqlStore{
+		db: db,
+		isDuplicateEntry: func(err error) bool {
+			var sqliteErr *sqlite.Error
+
+			return errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE)
+		},
+		expiredCondition: "reservation IS NOT NULL AND reservation < datetime('now', '-' || (CASE WHEN confirmation IS NULL THEN ? ELSE ? END) || ' seconds')",
+	}
+}
@@ -0,0 +1,1734 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// namespaces of the svg-elements and -attributes that are drawn, elements of other namespaces (e.g. of inkscape) are skipped
+const (
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+	xmlNamespace   = "http://www.w3.org/XML/1998/namespace"
+)
+
+// maximum depth of nested use-elements and gradient-references, to stop reference-cycles
+const svgMaxReferenceDepth = 16
+
+// font-size of texts without one, in user-units
+const svgDefaultFontSize = 16
+
+// factor for the control-points of cubic bézier-curves approximating a quarter of an ellipse
+const svgKappa = 0.5522847498
+
+// element of a parsed svg-document, nodes without a name hold the character-data of their parent
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	children []*svgNode
+	text     string
+}
+
+// parses an svg-document into a tree of its svg-elements
+func parseSVG(r io.Reader) (*svgNode, error) {
+	decoder := xml.NewDecoder(r)
+
+	var root *svgNode
+	var stack []*svgNode
+
+	// depth inside an element of a foreign namespace
+	skip := 0
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || (t.Name.Space != "" && t.Name.Space != svgNamespace) {
+				skip++
+
+				continue
+			}
+
+			node := &svgNode{name: t.Name.Local, attrs: map[string]string{}}
+
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "" || (attr.Name.Space == xlinkNamespace && attr.Name.Local == "href") {
+					node.attrs[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == xmlNamespace && attr.Name.Local == "space" {
+					node.attrs["xml:space"] = attr.Value
+				}
+			}
+
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+
+			stack = append(stack, node)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+			} else if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if skip == 0 && len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, &svgNode{text: string(t)})
+			}
+		}
+	}
+
+	if root == nil || root.name != "svg" {
+		return nil, errors.New("document has no svg-element")
+	}
+
+	return root, nil
+}
+
+// affine transformation [a b c d e f] of the svg-coordinates
+type svgMatrix [6]float64
+
+var svgIdentity = svgMatrix{1, 0, 0, 1, 0, 0}
+
+// returns the transformation that applies n first and then m
+func (m svgMatrix) multiply(n svgMatrix) svgMatrix {
+	return svgMatrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func svgTranslate(x, y float64) svgMatrix {
+	return svgMatrix{1, 0, 0, 1, x, y}
+}
+
+func svgScale(x, y float64) svgMatrix {
+	return svgMatrix{x, 0, 0, y, 0, 0}
+}
+
+// regex to match the functions of a transform-attribute, e.g. "translate(10, 20)"
+var svgTransformRegex = regexp.MustCompile(`([a-zA-Z]+)\s*\(([^)]*)\)`)
+
+// regex to split lists of numbers
+var svgListRegex = regexp.MustCompile(`[\s,]+`)
+
+// parses a whitespace- or comma-separated list of numbers
+func parseSVGNumbers(list string) ([]float64, error) {
+	var numbers []float64
+
+	for _, field := range svgListRegex.Split(strings.TrimSpace(list), -1) {
+		if field == "" {
+			continue
+		} else if number, err := strconv.ParseFloat(field, 64); err != nil {
+			return nil, err
+		} else {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers, nil
+}
+
+// parses the value of a transform-attribute
+func parseSVGTransform(transform string) (svgMatrix, error) {
+	m := svgIdentity
+
+	for _, match := range svgTransformRegex.FindAllStringSubmatch(transform, -1) {
+		args, err := parseSVGNumbers(match[2])
+
+		if err != nil {
+			return m, fmt.Errorf("invalid transform %q: %v", match[0], err)
+		}
+
+		// fill the optional arguments with their defaults
+		arg := func(ii int, fallback float64) float64 {
+			if ii < len(args) {
+				return args[ii]
+			} else {
+				return fallback
+			}
+		}
+
+		var t svgMatrix
+
+		switch match[1] {
+		case "matrix":
+			if len(args) != 6 {
+				return m, fmt.Errorf("invalid transform %q", match[0])
+			}
+
+			t = svgMatrix(args)
+		case "translate":
+			t = svgTranslate(arg(0, 0), arg(1, 0))
+		case "scale":
+			t = svgScale(arg(0, 1), arg(1, arg(0, 1)))
+		case "rotate":
+			sin, cos := math.Sincos(arg(0, 0) * math.Pi / 180)
+			cx, cy := arg(1, 0), arg(2, 0)
+
+			t = svgTranslate(cx, cy).multiply(svgMatrix{cos, sin, -sin, cos, 0, 0}).multiply(svgTranslate(-cx, -cy))
+		case "skewX":
+			t = svgMatrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = svgMatrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, fmt.Errorf("unknown transform %q", match[1])
+		}
+
+		m = m.multiply(t)
+	}
+
+	return m, nil
+}
+
+// sizes of the absolute length-units in user-units (css-pixels)
+var svgUnits = map[string]float64{
+	"px": 1,
+	"pt": 96.0 / 72,
+	"pc": 16,
+	"mm": 96 / 25.4,
+	"cm": 96 / 2.54,
+	"in": 96,
+	"em": svgDefaultFontSize,
+	"ex": svgDefaultFontSize / 2,
+}
+
+// parses an svg-length into user-units, percentages are relative to the reference
+func parseSVGLength(value string, reference float64) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	if number, ok := strings.CutSuffix(value, "%"); ok {
+		percentage, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+
+		return percentage * reference / 100, err
+	}
+
+	factor := 1.0
+
+	if len(value) > 2 {
+		if unit, ok := svgUnits[value[len(value)-2:]]; ok {
+			value = value[:len(value)-2]
+			factor = unit
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+	return number * factor, err
+}
+
+// rgb-color of the fills, strokes and texts
+type svgColor struct {
+	r, g, b int
+}
+
+// colors by their name, limited to the common ones
+var svgNamedColors = map[string]svgColor{
+	"black":     {0, 0, 0},
+	"white":     {255, 255, 255},
+	"red":       {255, 0, 0},
+	"green":     {0, 128, 0},
+	"blue":      {0, 0, 255},
+	"yellow":    {255, 255, 0},
+	"orange":    {255, 165, 0},
+	"gold":      {255, 215, 0},
+	"gray":      {128, 128, 128},
+	"grey":      {128, 128, 128},
+	"darkgray":  {169, 169, 169},
+	"darkgrey":  {169, 169, 169},
+	"lightgray": {211, 211, 211},
+	"lightgrey": {211, 211, 211},
+	"silver":    {192, 192, 192},
+	"maroon":    {128, 0, 0},
+	"darkred":   {139, 0, 0},
+	"brown":     {165, 42, 42},
+	"olive":     {128, 128, 0},
+	"lime":      {0, 255, 0},
+	"darkgreen": {0, 100, 0},
+	"teal":      {0, 128, 128},
+	"aqua":      {0, 255, 255},
+	"cyan":      {0, 255, 255},
+	"navy":      {0, 0, 128},
+	"darkblue":  {0, 0, 139},
+	"purple":    {128, 0, 128},
+	"fuchsia":   {255, 0, 255},
+	"magenta":   {255, 0, 255},
+	"pink":      {255, 192, 203},
+}
+
+// parses a css-color, the alpha-channel of colors that have one is ignored
+func parseSVGColor(value string) (svgColor, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok {
+		// expand the short forms "#rgb" and "#rgba"
+		if len(hex) == 3 || len(hex) == 4 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+
+		if len(hex) != 6 && len(hex) != 8 {
+			return svgColor{}, false
+		} else if rgb, err := strconv.ParseUint(hex[:6], 16, 32); err != nil {
+			return svgColor{}, false
+		} else {
+			return svgColor{int(rgb >> 16), int(rgb >> 8 & 0xff), int(rgb & 0xff)}, true
+		}
+	} else if args, ok := strings.CutPrefix(value, "rgb"); ok {
+		args = strings.TrimPrefix(args, "a")
+
+		if !strings.HasPrefix(args, "(") || !strings.HasSuffix(args, ")") {
+			return svgColor{}, false
+		}
+
+		fields := svgListRegex.Split(strings.TrimSpace(args[1:len(args)-1]), -1)
+
+		if len(fields) < 3 {
+			return svgColor{}, false
+		}
+
+		var channels [3]int
+
+		for ii := range channels {
+			if channel, err := parseSVGLength(fields[ii], 255); err != nil {
+				return svgColor{}, false
+			} else {
+				channels[ii] = int(math.Round(min(max(channel, 0), 255)))
+			}
+		}
+
+		return svgColor{channels[0], channels[1], channels[2]}, true
+	} else {
+		color, ok := svgNamedColors[value]
+
+		return color, ok
+	}
+}
+
+// presentation-properties that are inherited by the child-elements
+var svgInheritedProperties = []string{
+	"color",
+	"fill",
+	"fill-opacity",
+	"fill-rule",
+	"font-family",
+	"font-size",
+	"font-style",
+	"font-weight",
+	"stroke",
+	"stroke-dasharray",
+	"stroke-dashoffset",
+	"stroke-linecap",
+	"stroke-linejoin",
+	"stroke-opacity",
+	"stroke-width",
+	"text-anchor",
+	"visibility",
+}
+
+// css-rule of a style-element, only simple selectors of an element-name, class or id are supported
+type svgRule struct {
+	selector     string
+	specificity  int
+	declarations map[string]string
+}
+
+// matches the selectors "name", ".class", "#id" and "name.class"
+var svgSelectorRegex = regexp.MustCompile(`^([a-zA-Z][\w-]*)?([.#][\w-]+)?$`)
+
+// parses the declarations of a style-attribute or css-rule
+func parseSVGDeclarations(style string) map[string]string {
+	declarations := map[string]string{}
+
+	for _, declaration := range strings.Split(style, ";") {
+		if property, value, ok := strings.Cut(declaration, ":"); ok {
+			declarations[strings.TrimSpace(property)] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		}
+	}
+
+	return declarations
+}
+
+// parses the style-sheets of a document, sorted by their specificity
+func parseSVGStyleSheet(css string) []svgRule {
+	// remove the comments
+	css = regexp.MustCompile(`(?s)/\*.*?\*/`).ReplaceAllString(css, "")
+
+	var rules []svgRule
+
+	for _, block := range strings.Split(css, "}") {
+		selectors, declarations, ok := strings.Cut(block, "{")
+
+		if !ok {
+			continue
+		}
+
+		parsed := parseSVGDeclarations(declarations)
+
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+
+			if match := svgSelectorRegex.FindStringSubmatch(selector); match == nil || selector == "" {
+				continue
+			} else {
+				specificity := 0
+
+				if match[1] != "" {
+					specificity += 1
+				}
+
+				if strings.HasPrefix(match[2], ".") {
+					specificity += 10
+				} else if strings.HasPrefix(match[2], "#") {
+					specificity += 100
+				}
+
+				rules = append(rules, svgRule{selector: selector, specificity: specificity, declarations: parsed})
+			}
+		}
+	}
+
+	slices.SortStableFunc(rules, func(a, b svgRule) int {
+		return a.specificity - b.specificity
+	})
+
+	return rules
+}
+
+// checks wether a css-rule applies to an element
+func (rule svgRule) matches(node *svgNode) bool {
+	match := svgSelectorRegex.FindStringSubmatch(rule.selector)
+
+	if match[1] != "" && match[1] != node.name {
+		return false
+	} else if class, ok := strings.CutPrefix(match[2], "."); ok {
+		return slices.Contains(strings.Fields(node.attrs["class"]), class)
+	} else if id, ok := strings.CutPrefix(match[2], "#"); ok {
+		return node.attrs["id"] == id
+	} else {
+		return true
+	}
+}
+
+// segment of a path in absolute coordinates: 'M' and 'L' with one point, 'C' with two control-points and the end, 'Z' without points
+type svgSegment struct {
+	op     byte
+	points []svgPoint
+}
+
+type svgPoint struct {
+	x, y float64
+}
+
+// collects the segments of a path and keeps track of the points the relative commands refer to
+type svgPathBuilder struct {
+	segments []svgSegment
+	current  svgPoint
+	start    svgPoint
+}
+
+func (b *svgPathBuilder) moveTo(p svgPoint) {
+	b.segments = append(b.segments, svgSegment{op: 'M', points: []svgPoint{p}})
+	b.current = p
+	b.start = p
+}
+
+func (b *svgPathBuilder) lineTo(p svgPoint) {
+	b.segments = append(b.segments, svgSegment{op: 'L', points: []svgPoint{p}})
+	b.current = p
+}
+
+func (b *svgPathBuilder) curveTo(c1, c2, p svgPoint) {
+	b.segments = append(b.segments, svgSegment{op: 'C', points: []svgPoint{c1, c2, p}})
+	b.current = p
+}
+
+func (b *svgPathBuilder) close() {
+	b.segments = append(b.segments, svgSegment{op: 'Z'})
+	b.current = b.start
+}
+
+// appends an elliptical arc as cubic bézier-curves, following the implementation-notes of the svg-specification
+func (b *svgPathBuilder) arcTo(rx, ry, rotation float64, largeArc, sweep bool, p svgPoint) {
+	p0 := b.current
+
+	if p0 == p {
+		return
+	} else if rx == 0 || ry == 0 {
+		b.lineTo(p)
+
+		return
+	}
+
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	sinPhi, cosPhi := math.Sincos(rotation * math.Pi / 180)
+
+	// midpoint between the endpoints in the rotated coordinate-system
+	dx, dy := (p0.x-p.x)/2, (p0.y-p.y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	// enlarge radii that are too small to reach the endpoint
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+
+	numerator := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	denominator := rx*rx*y1*y1 + ry*ry*x1*x1
+
+	coefficient := 0.0
+	if numerator > 0 && denominator != 0 {
+		coefficient = math.Sqrt(numerator / denominator)
+	}
+
+	if largeArc == sweep {
+		coefficient = -coefficient
+	}
+
+	cx1 := coefficient * rx * y1 / ry
+	cy1 := -coefficient * ry * x1 / rx
+
+	cx := cosPhi*cx1 - sinPhi*cy1 + (p0.x+p.x)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (p0.y+p.y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// split the arc into parts of at most a quarter of the ellipse
+	parts := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(parts)
+	k := 4.0 / 3 * math.Tan(step/4)
+
+	point := func(t float64) svgPoint {
+		sin, cos := math.Sincos(t)
+
+		return svgPoint{cx + rx*cosPhi*cos - ry*sinPhi*sin, cy + rx*sinPhi*cos + ry*cosPhi*sin}
+	}
+
+	derivative := func(t float64) svgPoint {
+		sin, cos := math.Sincos(t)
+
+		return svgPoint{-rx*cosPhi*sin - ry*sinPhi*cos, -rx*sinPhi*sin + ry*cosPhi*cos}
+	}
+
+	for ii := range parts {
+		t1 := theta + float64(ii)*step
+		t2 := t1 + step
+
+		start, end := point(t1), point(t2)
+		d1, d2 := derivative(t1), derivative(t2)
+
+		// end exactly at the given point
+		if ii == parts-1 {
+			end = p
+		}
+
+		b.curveTo(svgPoint{start.x + k*d1.x, start.y + k*d1.y}, svgPoint{end.x - k*d2.x, end.y - k*d2.y}, end)
+	}
+}
+
+// appends an ellipse as four cubic bézier-curves
+func (b *svgPathBuilder) ellipse(cx, cy, rx, ry float64) {
+	kx, ky := svgKappa*rx, svgKappa*ry
+
+	b.moveTo(svgPoint{cx + rx, cy})
+	b.curveTo(svgPoint{cx + rx, cy + ky}, svgPoint{cx + kx, cy + ry}, svgPoint{cx, cy + ry})
+	b.curveTo(svgPoint{cx - kx, cy + ry}, svgPoint{cx - rx, cy + ky}, svgPoint{cx - rx, cy})
+	b.curveTo(svgPoint{cx - rx, cy - ky}, svgPoint{cx - kx, cy - ry}, svgPoint{cx, cy - ry})
+	b.curveTo(svgPoint{cx + kx, cy - ry}, svgPoint{cx + rx, cy - ky}, svgPoint{cx + rx, cy})
+	b.close()
+}
+
+// reads the numbers and flags of the path-data
+type svgPathScanner struct {
+	data string
+	pos  int
+}
+
+func (s *svgPathScanner) skipSeparators() {
+	for s.pos < len(s.data) && strings.IndexByte(" \t\r\n,", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+// checks wether a number follows, so the previous command is repeated
+func (s *svgPathScanner) hasNumber() bool {
+	s.skipSeparators()
+
+	return s.pos < len(s.data) && strings.IndexByte("+-.0123456789", s.data[s.pos]) >= 0
+}
+
+func (s *svgPathScanner) number() (float64, error) {
+	s.skipSeparators()
+
+	start := s.pos
+
+	digits := func() int {
+		begin := s.pos
+
+		for s.pos < len(s.data) && s.data[s.pos] >= '0' && s.data[s.pos] <= '9' {
+			s.pos++
+		}
+
+		return s.pos - begin
+	}
+
+	if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+		s.pos++
+	}
+
+	count := digits()
+
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		count += digits()
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("expected number at position %d of the path-data", start)
+	}
+
+	// exponent, only if digits follow, since "e" isn't a path-command
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		mark := s.pos
+		s.pos++
+
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+
+		if digits() == 0 {
+			s.pos = mark
+		}
+	}
+
+	return strconv.ParseFloat(s.data[start:s.pos], 64)
+}
+
+func (s *svgPathScanner) point() (svgPoint, error) {
+	if x, err := s.number(); err != nil {
+		return svgPoint{}, err
+	} else if y, err := s.number(); err != nil {
+		return svgPoint{}, err
+	} else {
+		return svgPoint{x, y}, nil
+	}
+}
+
+// flags of the arcs are single digits, that don't need to be separated
+func (s *svgPathScanner) flag() (bool, error) {
+	s.skipSeparators()
+
+	if s.pos < len(s.data) && (s.data[s.pos] == '0' || s.data[s.pos] == '1') {
+		s.pos++
+
+		return s.data[s.pos-1] == '1', nil
+	} else {
+		return false, fmt.Errorf("expected flag at position %d of the path-data", s.pos)
+	}
+}
+
+// parses the path-data of a d-attribute, like the browsers it returns the segments up to the first error
+func parseSVGPath(data string) ([]svgSegment, error) {
+	var b svgPathBuilder
+
+	s := svgPathScanner{data: data}
+
+	var command byte
+
+	// second control-point of the previous cubic and the control-point of the previous quadratic curve, for the smooth curves
+	var cubicControl, quadraticControl svgPoint
+	var previous byte
+
+	for {
+		s.skipSeparators()
+
+		if s.pos >= len(s.data) {
+			return b.segments, nil
+		}
+
+		if c := s.data[s.pos]; strings.IndexByte("MmLlHhVvCcSsQqTtAaZz", c) >= 0 {
+			command = c
+			s.pos++
+		} else if command == 0 || !s.hasNumber() {
+			return b.segments, fmt.Errorf("unexpected %q at position %d of the path-data", c, s.pos)
+		} else if command == 'M' {
+			// coordinates after a move are implicit lines
+			command = 'L'
+		} else if command == 'm' {
+			command = 'l'
+		}
+
+		relative := command >= 'a'
+
+		// makes a point of the path-data absolute
+		offset := func(p svgPoint) svgPoint {
+			if relative {
+				return svgPoint{p.x + b.current.x, p.y + b.current.y}
+			} else {
+				return p
+			}
+		}
+
+		upper := command &^ 0x20
+
+		switch upper {
+		case 'Z':
+			b.close()
+		case 'M', 'L', 'T':
+			if p, err := s.point(); err != nil {
+				return b.segments, err
+			} else if p = offset(p); upper == 'M' {
+				b.moveTo(p)
+			} else if upper == 'L' {
+				b.lineTo(p)
+			} else {
+				// reflect the control-point of a previous quadratic curve
+				control := b.current
+
+				if previous == 'Q' || previous == 'T' {
+					control = svgPoint{2*b.current.x - quadraticControl.x, 2*b.current.y - quadraticControl.y}
+				}
+
+				b.curveTo(quadraticToCubic(b.current, control, p))
+
+				quadraticControl = control
+			}
+		case 'H', 'V':
+			if v, err := s.number(); err != nil {
+				return b.segments, err
+			} else if p := b.current; upper == 'H' {
+				p.x = v
+
+				if relative {
+					p.x += b.current.x
+				}
+
+				b.lineTo(p)
+			} else {
+				p.y = v
+
+				if relative {
+					p.y += b.current.y
+				}
+
+				b.lineTo(p)
+			}
+		case 'C', 'S':
+			c1 := b.current
+
+			if upper == 'C' {
+				if p, err := s.point(); err != nil {
+					return b.segments, err
+				} else {
+					c1 = offset(p)
+				}
+			} else if previous == 'C' || previous == 'S' {
+				c1 = svgPoint{2*b.current.x - cubicControl.x, 2*b.current.y - cubicControl.y}
+			}
+
+			if c2, err := s.point(); err != nil {
+				return b.segments, err
+			} else if p, err := s.point(); err != nil {
+				return b.segments, err
+			} else {
+				c2, p = offset(c2), offset(p)
+
+				b.curveTo(c1, c2, p)
+
+				cubicControl = c2
+			}
+		case 'Q':
+			if control, err := s.point(); err != nil {
+				return b.segments, err
+			} else if p, err := s.point(); err != nil {
+				return b.segments, err
+			} else {
+				control, p = offset(control), offset(p)
+
+				b.curveTo(quadraticToCubic(b.current, control, p))
+
+				quadraticControl = control
+			}
+		case 'A':
+			if rx, err := s.number(); err != nil {
+				return b.segments, err
+			} else if ry, err := s.number(); err != nil {
+				return b.segments, err
+			} else if rotation, err := s.number(); err != nil {
+				return b.segments, err
+			} else if largeArc, err := s.flag(); err != nil {
+				return b.segments, err
+			} else if sweep, err := s.flag(); err != nil {
+				return b.segments, err
+			} else if p, err := s.point(); err != nil {
+				return b.segments, err
+			} else {
+				b.arcTo(rx, ry, rotation, largeArc, sweep, offset(p))
+			}
+		}
+
+		previous = upper
+	}
+}
+
+// returns the control-points and the end of the cubic curve that equals a quadratic one
+func quadraticToCubic(p0, control, p svgPoint) (svgPoint, svgPoint, svgPoint) {
+	return svgPoint{p0.x + 2.0/3*(control.x-p0.x), p0.y + 2.0/3*(control.y-p0.y)},
+		svgPoint{p.x + 2.0/3*(control.x-p.x), p.y + 2.0/3*(control.y-p.y)},
+		p
+}
+
+// font for the texts of the svg-documents
+type svgFont struct {
+	family string
+	bold   bool
+	italic bool
+	data   []byte
+}
+
+// draws an svg-document into a pdf
+type svgCanvas struct {
+	pdf   *fpdf.Fpdf
+	ids   map[string]*svgNode
+	rules []svgRule
+	// registered fonts by their lowercase family and pdf-style
+	fonts map[string]bool
+	// wether a default-font is registered, otherwise helvetica is used, which needs the texts in cp1252
+	defaultFont bool
+	translate   func(string) string
+	// registered images by their reference
+	images map[string]svgImage
+	// size of the viewport in user-units, the reference of percentages
+	width, height float64
+	// depth of the followed references
+	depth int
+}
+
+type svgImage struct {
+	name string
+	info *fpdf.ImageInfoType
+}
+
+// family under which the fonts of the svg-documents are registered, kept apart from the core-fonts of the pdf
+func svgFontFamily(family string) string {
+	return "svg-" + strings.ToLower(family)
+}
+
+// renders an svg-document on a pdf-page of the same size, texts use the fonts with their font-family or the first one
+func renderSVG(root *svgNode, fonts []svgFont) (*fpdf.Fpdf, error) {
+	viewBox, _ := parseSVGNumbers(root.attrs["viewBox"])
+
+	if len(viewBox) != 4 || viewBox[2] <= 0 || viewBox[3] <= 0 {
+		viewBox = nil
+	}
+
+	// size of the page in user-units, falling back to the view-box
+	size := func(attribute string, ii int) (float64, error) {
+		if value := root.attrs[attribute]; value != "" && !strings.HasSuffix(value, "%") {
+			return parseSVGLength(value, 0)
+		} else if viewBox != nil {
+			return viewBox[ii+2], nil
+		} else {
+			return 0, fmt.Errorf("svg has no %s", attribute)
+		}
+	}
+
+	width, err := size("width", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := size("height", 1)
+	if err != nil {
+		return nil, err
+	} else if width <= 0 || height <= 0 {
+		return nil, errors.New("svg has no size")
+	}
+
+	// the pdf uses millimeters, the user-units are css-pixels
+	pxToMM := 25.4 / 96
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{OrientationStr: "P", UnitStr: "mm", Size: fpdf.SizeType{Wd: width * pxToMM, Ht: height * pxToMM}})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	c := svgCanvas{
+		pdf:    pdf,
+		ids:    map[string]*svgNode{},
+		fonts:  map[string]bool{},
+		images: map[string]svgImage{},
+		width:  width,
+		height: height,
+	}
+
+	// the first font is used for texts with unknown font-families
+	for ii, font := range fonts {
+		style := pdfFontStyle(font.bold, font.italic)
+
+		if ii == 0 {
+			pdf.AddUTF8FontFromBytes(nativeFontFamily, "", font.data)
+
+			c.defaultFont = true
+		}
+
+		if font.family != "" {
+			pdf.AddUTF8FontFromBytes(svgFontFamily(font.family), style, font.data)
+
+			c.fonts[strings.ToLower(font.family)+"/"+style] = true
+		}
+	}
+
+	if !c.defaultFont {
+		c.translate = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+
+	// map the view-box onto the page
+	base := svgScale(pxToMM, pxToMM)
+
+	if viewBox != nil {
+		c.width, c.height = viewBox[2], viewBox[3]
+
+		x, y, w, h := alignSVGBox(root.attrs["preserveAspectRatio"], 0, 0, width, height, viewBox[2], viewBox[3])
+
+		base = base.multiply(svgTranslate(x, y)).multiply(svgScale(w/viewBox[2], h/viewBox[3])).multiply(svgTranslate(-viewBox[0], -viewBox[1]))
+	}
+
+	// collect the referenceable elements and the style-sheets
+	var css strings.Builder
+
+	var collect func(node *svgNode)
+	collect = func(node *svgNode) {
+		if id := node.attrs["id"]; id != "" {
+			c.ids[id] = node
+		}
+
+		for _, child := range node.children {
+			if node.name == "style" {
+				css.WriteString(child.text)
+			}
+
+			collect(child)
+		}
+	}
+
+	collect(root)
+
+	c.rules = parseSVGStyleSheet(css.String())
+
+	props, opacity, display := c.style(root, map[string]string{})
+
+	if display != "none" {
+		if err := c.drawChildren(root, base, props, opacity); err != nil {
+			return nil, err
+		}
+	}
+
+	if pdf.Err() {
+		return nil, pdf.Error()
+	}
+
+	return pdf, nil
+}
+
+// returns the style of the pdf-fonts
+func pdfFontStyle(bold, italic bool) string {
+	style := ""
+
+	if bold {
+		style += "B"
+	}
+
+	if italic {
+		style += "I"
+	}
+
+	return style
+}
+
+// fits a box of the given size into the viewport according to the preserveAspectRatio-attribute
+func alignSVGBox(preserveAspectRatio string, x, y, width, height, boxWidth, boxHeight float64) (float64, float64, float64, float64) {
+	fields := strings.Fields(preserveAspectRatio)
+
+	align := "xMidYMid"
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+
+	if align == "none" {
+		return x, y, width, height
+	}
+
+	// "slice" would need clipping, so the box is always fitted completely
+	scale := min(width/boxWidth, height/boxHeight)
+	w, h := boxWidth*scale, boxHeight*scale
+
+	if strings.Contains(align, "xMid") {
+		x += (width - w) / 2
+	} else if strings.Contains(align, "xMax") {
+		x += width - w
+	}
+
+	if strings.Contains(align, "YMid") {
+		y += (height - h) / 2
+	} else if strings.Contains(align, "YMax") {
+		y += height - h
+	}
+
+	return x, y, w, h
+}
+
+// computes the properties of an element from the inherited ones, its presentation-attributes, the style-sheets
+// and its style-attribute. Returns the inheritable properties, its opacity and its display-property
+func (c *svgCanvas) style(node *svgNode, inherited map[string]string) (map[string]string, float64, string) {
+	declarations := map[string]string{}
+
+	for _, property := range append(svgInheritedProperties, "opacity", "display", "stop-color") {
+		if value, ok := node.attrs[property]; ok {
+			declarations[property] = value
+		}
+	}
+
+	for _, rule := range c.rules {
+		if rule.matches(node) {
+			for property, value := range rule.declarations {
+				declarations[property] = value
+			}
+		}
+	}
+
+	for property, value := range parseSVGDeclarations(node.attrs["style"]) {
+		declarations[property] = value
+	}
+
+	props := make(map[string]string, len(inherited))
+
+	for property, value := range inherited {
+		props[property] = value
+	}
+
+	for _, property := range svgInheritedProperties {
+		if value, ok := declarations[property]; ok && value != "inherit" {
+			props[property] = value
+		}
+	}
+
+	// resolve relative font-sizes, so they don't refer to the size of the child-elements
+	if value, ok := declarations["font-size"]; ok && value != "inherit" {
+		parent := float64(svgDefaultFontSize)
+
+		if size, err := strconv.ParseFloat(inherited["font-size"], 64); err == nil {
+			parent = size
+		}
+
+		size, err := parseSVGLength(value, parent)
+
+		if number, ok := strings.CutSuffix(value, "em"); ok && !strings.HasSuffix(number, "r") {
+			size, err = strconv.ParseFloat(strings.TrimSpace(number), 64)
+			size *= parent
+		}
+
+		if err != nil {
+			props["font-size"] = inherited["font-size"]
+		} else {
+			props["font-size"] = strconv.FormatFloat(size, 'f', -1, 64)
+		}
+	}
+
+	opacity := 1.0
+
+	if value, ok := declarations["opacity"]; ok {
+		if number, err := parseSVGLength(value, 1); err == nil {
+			opacity = min(max(number, 0), 1)
+		}
+	}
+
+	props["stop-color"] = declarations["stop-color"]
+
+	return props, opacity, declarations["display"]
+}
+
+// elements that aren't drawn where they are defined
+var svgUndrawnElements = []string{
+	"clipPath", "defs", "desc", "filter", "linearGradient", "marker", "mask", "metadata", "pattern", "radialGradient", "script", "style", "symbol", "title",
+}
+
+func (c *svgCanvas) drawChildren(node *svgNode, m svgMatrix, props map[string]string, alpha float64) error {
+	for _, child := range node.children {
+		if child.name != "" {
+			if err := c.drawNode(child, m, props, alpha); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *svgCanvas) drawNode(node *svgNode, m svgMatrix, inherited map[string]string, alpha float64) error {
+	if slices.Contains(svgUndrawnElements, node.name) {
+		return nil
+	}
+
+	props, opacity, display := c.style(node, inherited)
+
+	if display == "none" {
+		return nil
+	}
+
+	alpha *= opacity
+
+	if transform, ok := node.attrs["transform"]; ok {
+		if t, err := parseSVGTransform(transform); err != nil {
+			return err
+		} else {
+			m = m.multiply(t)
+		}
+	}
+
+	switch node.name {
+	case "svg", "g", "a", "switch":
+		// nested svg-elements only move their content
+		if node.name == "svg" {
+			m = m.multiply(svgTranslate(c.length(node, "x", c.width), c.length(node, "y", c.height)))
+		}
+
+		return c.drawChildren(node, m, props, alpha)
+	case "use":
+		target := c.ids[strings.TrimPrefix(c.href(node), "#")]
+
+		if target == nil || c.depth >= svgMaxReferenceDepth {
+			return nil
+		}
+
+		m = m.multiply(svgTranslate(c.length(node, "x", c.width), c.length(node, "y", c.height)))
+
+		c.depth++
+		defer func() { c.depth-- }()
+
+		// symbols are only drawn through a use-element
+		if target.name == "symbol" {
+			targetProps, targetOpacity, _ := c.style(target, props)
+
+			return c.drawChildren(target, m, targetProps, alpha*targetOpacity)
+		} else {
+			return c.drawNode(target, m, props, alpha)
+		}
+	case "text":
+		return c.drawText(node, m, props, alpha)
+	case "image":
+		return c.drawImage(node, m, alpha)
+	default:
+		if segments := c.shape(node); len(segments) > 0 {
+			c.drawPath(segments, m, props, alpha)
+		}
+
+		return nil
+	}
+}
+
+// returns the reference of an use- or image-element
+func (c *svgCanvas) href(node *svgNode) string {
+	return strings.TrimSpace(node.attrs["href"])
+}
+
+// returns a length-attribute of an element in user-units, 0 if it is missing or invalid
+func (c *svgCanvas) length(node *svgNode, attribute string, reference float64) float64 {
+	if value, err := parseSVGLength(node.attrs[attribute], reference); err != nil {
+		return 0
+	} else {
+		return value
+	}
+}
+
+// returns the path of a basic shape or path-element
+func (c *svgCanvas) shape(node *svgNode) []svgSegment {
+	var b svgPathBuilder
+
+	diagonal := math.Hypot(c.width, c.height) / math.Sqrt2
+
+	switch node.name {
+	case "path":
+		// like the browsers, draw the path up to an error in its data
+		segments, _ := parseSVGPath(node.attrs["d"])
+
+		return segments
+	case "rect":
+		x, y := c.length(node, "x", c.width), c.length(node, "y", c.height)
+		w, h := c.length(node, "width", c.width), c.length(node, "height", c.height)
+
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+
+		// a missing radius takes the value of the other one
+		rx, hasRX := node.attrs["rx"]
+		ry, hasRY := node.attrs["ry"]
+
+		if !hasRX {
+			rx = ry
+		} else if !hasRY {
+			ry = rx
+		}
+
+		radiusX, _ := parseSVGLength(rx, c.width)
+		radiusY, _ := parseSVGLength(ry, c.height)
+
+		radiusX = min(max(radiusX, 0), w/2)
+		radiusY = min(max(radiusY, 0), h/2)
+
+		if radiusX == 0 || radiusY == 0 {
+			b.moveTo(svgPoint{x, y})
+			b.lineTo(svgPoint{x + w, y})
+			b.lineTo(svgPoint{x + w, y + h})
+			b.lineTo(svgPoint{x, y + h})
+		} else {
+			kx, ky := svgKappa*radiusX, svgKappa*radiusY
+
+			b.moveTo(svgPoint{x + radiusX, y})
+			b.lineTo(svgPoint{x + w - radiusX, y})
+			b.curveTo(svgPoint{x + w - radiusX + kx, y}, svgPoint{x + w, y + radiusY - ky}, svgPoint{x + w, y + radiusY})
+			b.lineTo(svgPoint{x + w, y + h - radiusY})
+			b.curveTo(svgPoint{x + w, y + h - radiusY + ky}, svgPoint{x + w - radiusX + kx, y + h}, svgPoint{x + w - radiusX, y + h})
+			b.lineTo(svgPoint{x + radiusX, y + h})
+			b.curveTo(svgPoint{x + radiusX - kx, y + h}, svgPoint{x, y + h - radiusY + ky}, svgPoint{x, y + h - radiusY})
+			b.lineTo(svgPoint{x, y + radiusY})
+			b.curveTo(svgPoint{x, y + radiusY - ky}, svgPoint{x + radiusX - kx, y}, svgPoint{x + radiusX, y})
+		}
+
+		b.close()
+	case "circle":
+		if r := c.length(node, "r", diagonal); r > 0 {
+			b.ellipse(c.length(node, "cx", c.width), c.length(node, "cy", c.height), r, r)
+		}
+	case "ellipse":
+		rx, ry := c.length(node, "rx", c.width), c.length(node, "ry", c.height)
+
+		if rx > 0 && ry > 0 {
+			b.ellipse(c.length(node, "cx", c.width), c.length(node, "cy", c.height), rx, ry)
+		}
+	case "line":
+		b.moveTo(svgPoint{c.length(node, "x1", c.width), c.length(node, "y1", c.height)})
+		b.lineTo(svgPoint{c.length(node, "x2", c.width), c.length(node, "y2", c.height)})
+	case "polyline", "polygon":
+		points, _ := parseSVGNumbers(node.attrs["points"])
+
+		for ii := 0; ii+1 < len(points); ii += 2 {
+			if ii == 0 {
+				b.moveTo(svgPoint{points[ii], points[ii+1]})
+			} else {
+				b.lineTo(svgPoint{points[ii], points[ii+1]})
+			}
+		}
+
+		if node.name == "polygon" && len(b.segments) > 0 {
+			b.close()
+		}
+	}
+
+	return b.segments
+}
+
+// starts drawing in the user-units of an element: the pdf-matrix maps the page-coordinates of go-pdf,
+// which are millimeters from the top-left corner, the same way the svg-matrix maps the user-units
+func (c *svgCanvas) begin(m svgMatrix) {
+	k := 72 / 25.4
+	_, h := c.pdf.GetPageSize()
+
+	c.pdf.TransformBegin()
+	c.pdf.Transform(fpdf.TransformMatrix{
+		A: m[0],
+		B: -m[1],
+		C: -m[2],
+		D: m[3],
+		E: k * (m[2]*h + m[4]),
+		F: k * (h - m[3]*h - m[5]),
+	})
+}
+
+// returns the color of a fill- or stroke-property, nil for "none"
+func (c *svgCanvas) paint(value string, props map[string]string) *svgColor {
+	value = strings.TrimSpace(value)
+
+	if value == "none" || value == "" {
+		return nil
+	} else if value == "currentColor" {
+		value = props["color"]
+	} else if reference, ok := strings.CutPrefix(value, "url("); ok {
+		// gradients and patterns are drawn in the color of their first stop or the fallback-color
+		id, fallback, _ := strings.Cut(reference, ")")
+
+		if color, ok := c.gradientColor(strings.Trim(strings.TrimSpace(id), `"'#`), 0); ok {
+			return &color
+		}
+
+		return c.paint(fallback, props)
+	}
+
+	if color, ok := parseSVGColor(value); ok {
+		return &color
+	} else {
+		return &svgColor{}
+	}
+}
+
+// returns the color of the first stop of a gradient
+func (c *svgCanvas) gradientColor(id string, depth int) (svgColor, bool) {
+	node := c.ids[id]
+
+	if node == nil || depth >= svgMaxReferenceDepth {
+		return svgColor{}, false
+	}
+
+	for _, child := range node.children {
+		if child.name == "stop" {
+			props, _, _ := c.style(child, map[string]string{})
+
+			if color, ok := parseSVGColor(props["stop-color"]); ok {
+				return color, true
+			} else {
+				return svgColor{}, true
+			}
+		}
+	}
+
+	// the stops can be inherited from another gradient
+	return c.gradientColor(strings.TrimPrefix(c.href(node), "#"), depth+1)
+}
+
+// returns a numeric property
+func numericProperty(props map[string]string, property string, fallback float64) float64 {
+	if value, ok := props[property]; !ok {
+		return fallback
+	} else if number, err := parseSVGLength(value, 1); err != nil {
+		return fallback
+	} else {
+		return number
+	}
+}
+
+func (c *svgCanvas) setAlpha(alpha float64) {
+	c.pdf.SetAlpha(min(max(alpha, 0), 1), "Normal")
+}
+
+func (c *svgCanvas) drawPath(segments []svgSegment, m svgMatrix, props map[string]string, alpha float64) {
+	if props["visibility"] == "hidden" || props["visibility"] == "collapse" {
+		return
+	}
+
+	fill := c.paint(cmpOr(props["fill"], "black"), props)
+	stroke := c.paint(props["stroke"], props)
+
+	strokeWidth := numericProperty(props, "stroke-width", 1)
+
+	if strokeWidth <= 0 {
+		stroke = nil
+	}
+
+	fillAlpha := alpha * numericProperty(props, "fill-opacity", 1)
+	strokeAlpha := alpha * numericProperty(props, "stroke-opacity", 1)
+
+	fillRule := ""
+	if props["fill-rule"] == "evenodd" {
+		fillRule = "*"
+	}
+
+	c.begin(m)
+	defer c.pdf.TransformEnd()
+
+	if fill != nil {
+		c.pdf.SetFillColor(fill.r, fill.g, fill.b)
+	}
+
+	if stroke != nil {
+		c.pdf.SetDrawColor(stroke.r, stroke.g, stroke.b)
+		c.pdf.SetLineWidth(strokeWidth)
+		c.pdf.SetLineCapStyle(props["stroke-linecap"])
+		c.pdf.SetLineJoinStyle(props["stroke-linejoin"])
+
+		dashes, _ := parseSVGNumbers(props["stroke-dasharray"])
+
+		// an odd number of dashes is repeated
+		if len(dashes)%2 == 1 {
+			dashes = append(dashes, dashes...)
+		}
+
+		c.pdf.SetDashPattern(dashes, numericProperty(props, "stroke-dashoffset", 0))
+	}
+
+	if fill != nil && stroke != nil && fillAlpha == strokeAlpha {
+		c.setAlpha(fillAlpha)
+		c.path(segments)
+		c.pdf.DrawPath("FD" + fillRule)
+	} else {
+		if fill != nil {
+			c.setAlpha(fillAlpha)
+			c.path(segments)
+			c.pdf.DrawPath("F" + fillRule)
+		}
+
+		if stroke != nil {
+			c.setAlpha(strokeAlpha)
+			c.path(segments)
+			c.pdf.DrawPath("D")
+		}
+	}
+}
+
+// like cmp.Or for the properties, which are empty if they aren't set
+func cmpOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	} else {
+		return value
+	}
+}
+
+func (c *svgCanvas) path(segments []svgSegment) {
+	for _, segment := range segments {
+		switch segment.op {
+		case 'M':
+			c.pdf.MoveTo(segment.points[0].x, segment.points[0].y)
+		case 'L':
+			c.pdf.LineTo(segment.points[0].x, segment.points[0].y)
+		case 'C':
+			c.pdf.CurveBezierCubicTo(segment.points[0].x, segment.points[0].y, segment.points[1].x, segment.points[1].y, segment.points[2].x, segment.points[2].y)
+		case 'Z':
+			c.pdf.ClosePath()
+		}
+	}
+}
+
+// selects the font of a text and returns the function that prepares the text for it
+func (c *svgCanvas) setFont(props map[string]string) func(string) string {
+	weight := props["font-weight"]
+	bold := weight == "bold" || weight == "bolder"
+
+	if number, err := strconv.Atoi(weight); err == nil {
+		bold = number >= 600
+	}
+
+	style := pdfFontStyle(bold, props["font-style"] == "italic" || props["font-style"] == "oblique")
+	size := numericProperty(props, "font-size", svgDefaultFontSize)
+
+	identity := func(s string) string { return s }
+
+	for _, family := range strings.Split(props["font-family"], ",") {
+		family = strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`))
+
+		for _, fontStyle := range slices.Compact([]string{style, ""}) {
+			if c.fonts[family+"/"+fontStyle] {
+				c.pdf.SetFont(svgFontFamily(family), fontStyle, 0)
+				c.pdf.SetFontUnitSize(size)
+
+				return identity
+			}
+		}
+	}
+
+	if c.defaultFont {
+		c.pdf.SetFont(nativeFontFamily, "", 0)
+		c.pdf.SetFontUnitSize(size)
+
+		return identity
+	} else {
+		c.pdf.SetFont("Helvetica", style, 0)
+		c.pdf.SetFontUnitSize(size)
+
+		return c.translate
+	}
+}
+
+// part of a text with the same style
+type svgTextRun struct {
+	text  string
+	props map[string]string
+	alpha float64
+	// absolute position that starts a new text-chunk
+	x, y *float64
+	// relative shift before the run
+	dx, dy float64
+	// keep the whitespace of the text
+	preserve bool
+}
+
+// collects the text-runs of a text-element and its tspans
+func (c *svgCanvas) textRuns(node *svgNode, props map[string]string, alpha float64, preserve bool, runs []svgTextRun, pending *svgTextRun) []svgTextRun {
+	if space, ok := node.attrs["xml:space"]; ok {
+		preserve = space == "preserve"
+	}
+
+	// positions of the element apply to its first run
+	first := func(attribute string, reference float64) *float64 {
+		if values := svgListRegex.Split(strings.TrimSpace(node.attrs[attribute]), -1); values[0] == "" {
+			return nil
+		} else if value, err := parseSVGLength(values[0], reference); err != nil {
+			return nil
+		} else {
+			return &value
+		}
+	}
+
+	if x := first("x", c.width); x != nil {
+		pending.x = x
+	}
+
+	if y := first("y", c.height); y != nil {
+		pending.y = y
+	}
+
+	if dx := first("dx", c.width); dx != nil {
+		pending.dx += *dx
+	}
+
+	if dy := first("dy", c.height); dy != nil {
+		pending.dy += *dy
+	}
+
+	for _, child := range node.children {
+		if child.name == "" {
+			run := *pending
+			run.text = child.text
+			run.props = props
+			run.alpha = alpha
+			run.preserve = preserve
+
+			runs = append(runs, run)
+
+			*pending = svgTextRun{}
+		} else if child.name == "tspan" || child.name == "a" || child.name == "textPath" {
+			childProps, opacity, display := c.style(child, props)
+
+			if display != "none" {
+				runs = c.textRuns(child, childProps, alpha*opacity, preserve, runs, pending)
+			}
+		}
+	}
+
+	return runs
+}
+
+// regex to collapse the whitespace of texts
+var svgSpaceRegex = regexp.MustCompile(` +`)
+
+func (c *svgCanvas) drawText(node *svgNode, m svgMatrix, props map[string]string, alpha float64) error {
+	runs := c.textRuns(node, props, alpha, false, nil, &svgTextRun{})
+
+	// normalize the whitespace like the browsers
+	for ii := range runs {
+		text := strings.NewReplacer("\r", "", "\t", " ").Replace(runs[ii].text)
+
+		if runs[ii].preserve {
+			text = strings.ReplaceAll(text, "\n", " ")
+		} else {
+			text = svgSpaceRegex.ReplaceAllString(strings.ReplaceAll(text, "\n", ""), " ")
+		}
+
+		runs[ii].text = text
+	}
+
+	// split the runs into chunks, which start at an absolute position
+	var chunks [][]svgTextRun
+
+	for ii, run := range runs {
+		if ii == 0 || run.x != nil || run.y != nil {
+			chunks = append(chunks, nil)
+		}
+
+		chunks[len(chunks)-1] = append(chunks[len(chunks)-1], run)
+	}
+
+	var x, y float64
+
+	for _, chunk := range chunks {
+		// remove the whitespace around the chunk, e.g. from the indentation between tspans
+		if !chunk[0].preserve {
+			chunk[0].text = strings.TrimLeft(chunk[0].text, " ")
+			chunk[len(chunk)-1].text = strings.TrimRight(chunk[len(chunk)-1].text, " ")
+		}
+
+		if chunk[0].x != nil {
+			x = *chunk[0].x
+		}
+
+		if chunk[0].y != nil {
+			y = *chunk[0].y
+		}
+
+		// measure the chunk to align it at its anchor
+		widths := make([]float64, len(chunk))
+		total := 0.0
+
+		for ii, run := range chunk {
+			translate := c.setFont(run.props)
+
+			widths[ii] = c.pdf.GetStringWidth(translate(run.text))
+			total += widths[ii] + run.dx
+		}
+
+		switch chunk[0].props["text-anchor"] {
+		case "middle":
+			x -= total / 2
+		case "end":
+			x -= total
+		}
+
+		for ii, run := range chunk {
+			x += run.dx
+			y += run.dy
+
+			fill := c.paint(cmpOr(run.props["fill"], "black"), run.props)
+
+			if fill != nil && run.text != "" && run.props["visibility"] != "hidden" && run.props["visibility"] != "collapse" {
+				c.begin(m)
+
+				translate := c.setFont(run.props)
+
+				// go-pdf uses the fill-color for texts with the same text-color
+				c.pdf.SetFillColor(fill.r, fill.g, fill.b)
+				c.pdf.SetTextColor(fill.r, fill.g, fill.b)
+				c.setAlpha(run.alpha * numericProperty(run.props, "fill-opacity", 1))
+				c.pdf.Text(x, y, translate(run.text))
+
+				c.pdf.TransformEnd()
+			}
+
+			x += widths[ii]
+		}
+	}
+
+	return nil
+}
+
+// registers the image of a reference, which is either a data-url or a file
+func (c *svgCanvas) image(href string) (svgImage, error) {
+	if image, ok := c.images[href]; ok {
+		return image, nil
+	}
+
+	var data []byte
+	var imageType string
+
+	if dataURL, ok := strings.CutPrefix(href, "data:"); ok {
+		meta, content, ok := strings.Cut(dataURL, ",")
+
+		if !ok {
+			return svgImage{}, errors.New("invalid data-url of image")
+		}
+
+		mediaType, encoding, _ := strings.Cut(meta, ";")
+
+		if encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+
+			if err != nil {
+				return svgImage{}, fmt.Errorf("invalid data-url of image: %v", err)
+			}
+
+			data = decoded
+		} else if decoded, err := url.PathUnescape(content); err != nil {
+			return svgImage{}, fmt.Errorf("invalid data-url of image: %v", err)
+		} else {
+			data = []byte(decoded)
+		}
+
+		imageType = strings.TrimPrefix(mediaType, "image/")
+	} else {
+		// relative paths are resolved from the working-directory of the backend
+		file := strings.TrimPrefix(href, "file://")
+
+		if content, err := os.ReadFile(file); err != nil {
+			return svgImage{}, err
+		} else {
+			data = content
+		}
+
+		imageType = strings.TrimPrefix(path.Ext(file), ".")
+	}
+
+	switch strings.ToLower(imageType) {
+	case "png":
+		imageType = "PNG"
+	case "jpeg", "jpg":
+		imageType = "JPG"
+	case "gif":
+		imageType = "GIF"
+	default:
+		return svgImage{}, fmt.Errorf("unsupported image-type %q", imageType)
+	}
+
+	image := svgImage{name: fmt.Sprintf("svg-image-%d", len(c.images))}
+	image.info = c.pdf.RegisterImageOptionsReader(image.name, fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+
+	if c.pdf.Err() {
+		return svgImage{}, c.pdf.Error()
+	}
+
+	c.images[href] = image
+
+	return image, nil
+}
+
+func (c *svgCanvas) drawImage(node *svgNode, m svgMatrix, alpha float64) error {
+	x, y := c.length(node, "x", c.width), c.length(node, "y", c.height)
+	w, h := c.length(node, "width", c.width), c.length(node, "height", c.height)
+
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+
+	image, err := c.image(c.href(node))
+
+	if err != nil {
+		return fmt.Errorf("can't load image of the svg: %v", err)
+	}
+
+	x, y, w, h = alignSVGBox(node.attrs["preserveAspectRatio"], x, y, w, h, image.info.Width(), image.info.Height())
+
+	c.begin(m)
+	defer c.pdf.TransformEnd()
+
+	c.setAlpha(alpha)
+	c.pdf.ImageOptions(image.name, x, y, w, h, false, fpdf.ImageOptions{}, 0, "")
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// checks two numbers for equality within the rounding-errors
+func approximately(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParseSVGTransform(t *testing.T) {
+	tests := map[string]svgMatrix{
+		"":                             svgIdentity,
+		"translate(10)":                {1, 0, 0, 1, 10, 0},
+		"translate(10, 20) scale(2)":   {2, 0, 0, 2, 10, 20},
+		"scale(2,3)":                   {2, 0, 0, 3, 0, 0},
+		"rotate(90)":                   {0, 1, -1, 0, 0, 0},
+		"rotate(90 10 10)":             {0, 1, -1, 0, 20, 0},
+		"matrix(1 2 3 4 5 6)":          {1, 2, 3, 4, 5, 6},
+		"scale(2) translate(10,-5)":    {2, 0, 0, 2, 20, -10},
+		"skewX(45)":                    {1, 0, 1, 1, 0, 0},
+		"translate(1e1 .5)rotate(180)": {-1, 0, 0, -1, 10, 0.5},
+	}
+
+	for transform, expected := range tests {
+		if m, err := parseSVGTransform(transform); err != nil {
+			t.Errorf("can't parse transform %q: %v", transform, err)
+		} else {
+			for ii := range m {
+				if !approximately(m[ii], expected[ii]) {
+					t.Errorf("transform %q is %v, expected %v", transform, m, expected)
+
+					break
+				}
+			}
+		}
+	}
+
+	for _, transform := range []string{"matrix(1 2 3)", "translate(a)", "wobble(2)"} {
+		if _, err := parseSVGTransform(transform); err == nil {
+			t.Errorf("invalid transform %q was accepted", transform)
+		}
+	}
+}
+
+func TestParseSVGPath(t *testing.T) {
+	tests := map[string]string{
+		"M10 20L30 40":             "M L",
+		"m10,20 30,40 h10 v-10z":   "M L L L Z",
+		"M0 0C1 1 2 2 3 3S5 5 6 6": "M C C",
+		"M0 0Q5 5 10 0T20 0":       "M C C",
+		"M0 0A10 10 0 1 1 20 0":    "M C C",
+		"M0 0a10 10 0 0020 0":      "M C C",
+		"M0 0A0 10 0 0 0 20 0":     "M L",
+	}
+
+	for data, expected := range tests {
+		segments, err := parseSVGPath(data)
+
+		ops := make([]string, len(segments))
+		for ii, segment := range segments {
+			ops[ii] = string(segment.op)
+		}
+
+		if err != nil {
+			t.Errorf("can't parse path %q: %v", data, err)
+		} else if result := strings.Join(ops, " "); result != expected {
+			t.Errorf("path %q has the segments %q, expected %q", data, result, expected)
+		}
+	}
+
+	// relative commands refer to the current point, a half circle ends at the given point
+	segments, _ := parseSVGPath("m10,20 30,40 h10 v-10 M0 0a10 10 0 0 1 20 0")
+
+	expected := []svgPoint{{10, 20}, {40, 60}, {50, 60}, {50, 50}, {0, 0}}
+	for ii, point := range expected {
+		if segments[ii].points[0] != point {
+			t.Errorf("segment %d ends at %v, expected %v", ii, segments[ii].points[0], point)
+		}
+	}
+
+	if end := segments[len(segments)-1].points[2]; !approximately(end.x, 20) || !approximately(end.y, 0) {
+		t.Errorf("arc ends at %v, expected {20 0}", end)
+	}
+
+	// the top of a half circle from (0, 0) to (20, 0) with the sweep-flag is at (10, -10)
+	if middle := segments[len(segments)-2].points[2]; !approximately(middle.x, 10) || !approximately(middle.y, -10) {
+		t.Errorf("arc passes %v, expected {10 -10}", middle)
+	}
+
+	// the segments up to an error are returned
+	if segments, err := parseSVGPath("M0 0L10 10L"); err == nil {
+		t.Error("incomplete path was accepted")
+	} else if len(segments) != 2 {
+		t.Errorf("incomplete path has %d segments, expected 2", len(segments))
+	}
+}
+
+func TestParseSVGColor(t *testing.T) {
+	tests := map[string]svgColor{
+		"#fff":               {255, 255, 255},
+		"#1A2b3C":            {26, 43, 60},
+		"#1a2b3c80":          {26, 43, 60},
+		"rgb(10, 20, 30)":    {10, 20, 30},
+		"rgba(10,20,30,0.5)": {10, 20, 30},
+		"rgb(100%, 0%, 50%)": {255, 0, 128},
+		" Navy ":             {0, 0, 128},
+	}
+
+	for value, expected := range tests {
+		if color, ok := parseSVGColor(value); !ok {
+			t.Errorf("can't parse color %q", value)
+		} else if color != expected {
+			t.Errorf("color %q is %v, expected %v", value, color, expected)
+		}
+	}
+
+	for _, value := range []string{"#12", "rgb(1,2)", "unknown", ""} {
+		if _, ok := parseSVGColor(value); ok {
+			t.Errorf("invalid color %q was accepted", value)
+		}
+	}
+}
+
+func TestParseSVGStyle(t *testing.T) {
+	root, err := parseSVG(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape">
+	<style>text { fill: red } .title { font-size: 2em } #name { fill: blue }</style>
+	<inkscape:page/>
+	<g font-size="20" fill="green">
+		<text class="title" id="name" style="font-weight: bold">{{.Name}}</text>
+		<text fill="black">Text</text>
+	</g>
+</svg>`))
+
+	if err != nil {
+		t.Fatalf("can't parse svg: %v", err)
+	}
+
+	c := svgCanvas{ids: map[string]*svgNode{}, rules: parseSVGStyleSheet("text { fill: red } .title { font-size: 2em } #name { fill: blue }")}
+
+	// the elements of other namespaces are skipped
+	var elements []string
+	var group *svgNode
+	for _, child := range root.children {
+		if child.name != "" {
+			elements = append(elements, child.name)
+		}
+
+		if child.name == "g" {
+			group = child
+		}
+	}
+
+	if strings.Join(elements, " ") != "style g" {
+		t.Fatalf("svg has the elements %v, expected style and g", elements)
+	}
+
+	groupProps, _, _ := c.style(group, map[string]string{})
+
+	var texts []*svgNode
+	for _, child := range group.children {
+		if child.name == "text" {
+			texts = append(texts, child)
+		}
+	}
+
+	// the id-selector wins over the element-selector and the presentation-attributes, the font-size is relative to the group
+	if props, _, _ := c.style(texts[0], groupProps); props["fill"] != "blue" || props["font-size"] != "40" || props["font-weight"] != "bold" {
+		t.Errorf("styled text has the properties %v", props)
+	}
+
+	// the style-sheet wins over the presentation-attributes
+	if props, _, _ := c.style(texts[1], groupProps); props["fill"] != "red" || props["font-size"] != "20" {
+		t.Errorf("text has the properties %v", props)
+	}
+
+	if _, err := parseSVG(strings.NewReader(`<html><svg/></html>`)); err == nil {
+		t.Error("document without svg-root was accepted")
+	}
+}
+
+func TestNativeRendererExport(t *testing.T) {
+	qrCode, err := qrDataURL("https://example.org/verify/ABCD")
+	if err != nil {
+		t.Fatalf("can't create qr-code: %v", err)
+	}
+
+	tpl, err := template.New("test").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="210mm" height="297mm" viewBox="0 0 210 297">
+	<defs>
+		<linearGradient id="gold"><stop offset="0" stop-color="#c9a227"/><stop offset="1" stop-color="#fff"/></linearGradient>
+		<path id="ornament" d="M0 0c5-5 10 5 15 0s10 5 15 0"/>
+	</defs>
+	<rect x="10" y="10" width="190" height="277" rx="5" fill="none" stroke="url(#gold)" stroke-width="2" stroke-dasharray="4 2"/>
+	<use xlink:href="#ornament" x="90" y="40" fill="none" stroke="black" opacity="0.5"/>
+	<circle cx="105" cy="200" r="20" fill="url(#gold)" fill-opacity="0.3"/>
+	<text x="105" y="120" text-anchor="middle" font-family="Unknown" font-size="10">{{.Name}}</text>
+	<text x="105" y="140" text-anchor="middle" font-size="6"><tspan>{{.Article}}</tspan> <tspan font-weight="bold">{{.Element}}</tspan></text>
+	<image x="170" y="252" width="25" height="25" href="{{.QRCode}}"/>
+</svg>`)
+	if err != nil {
+		t.Fatalf("can't parse template: %v", err)
+	}
+
+	data := SponsorshipTemplateData{Name: "Erika Mustermann", Article: "das Modul", Element: "A1", QRCode: qrCode}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		t.Fatalf("can't execute template: %v", err)
+	}
+
+	root, err := parseSVG(&buf)
+	if err != nil {
+		t.Fatalf("can't parse svg: %v", err)
+	}
+
+	pdf, err := renderSVG(root, nil)
+	if err != nil {
+		t.Fatalf("can't render svg: %v", err)
+	}
+
+	// the page has the size of the svg
+	if width, height := pdf.GetPageSize(); !approximately(width, 210) || !approximately(height, 297) {
+		t.Errorf("page has the size %.2fx%.2f, expected A4", width, height)
+	}
+
+	pdf.SetCompression(false)
+
+	var output bytes.Buffer
+	if err := pdf.Output(&output); err != nil {
+		t.Fatalf("can't write pdf: %v", err)
+	}
+
+	for _, text := range []string{"(Erika Mustermann) Tj", "(das Modul) Tj", "(A1) Tj", "/Subtype /Image"} {
+		if !bytes.Contains(output.Bytes(), []byte(text)) {
+			t.Errorf("pdf doesn't contain %q", text)
+		}
+	}
+
+	// the renderer only exports pdfs
+	var r nativeRenderer
+
+	if err := r.export(tpl, data, filepath.Join(t.TempDir(), "certificate.pdf")); err != nil {
+		t.Errorf("can't export certificate: %v", err)
+	}
+
+	if err := r.export(tpl, data, filepath.Join(t.TempDir(), "certificate.png")); err == nil {
+		t.Error("native renderer exported a png")
+	}
+}
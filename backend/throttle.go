@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// kinds of subjects whose login-failures are counted
+const (
+	throttleAccount = "account"
+	throttleIP      = "ip"
+	// password-reset-requests of an ip-address, counted apart from the logins so they can't lock an account
+	throttleReset = "reset"
+)
+
+// login-failures of an account or ip-address in the database
+type LoginThrottleDB struct {
+	Kind     string `json:"kind"`
+	Subject  string `json:"subject"`
+	Failures int    `json:"failures"`
+	Last     int64  `json:"last"`
+	Locked   int64  `json:"locked"`
+}
+
+// time until the next login-attempt is allowed
+func (throttle LoginThrottleDB) wait(now time.Time) time.Duration {
+	// locked subjects have to wait for the end of the lockout
+	if locked := time.Unix(throttle.Locked, 0); locked.After(now) {
+		return locked.Sub(now)
+	}
+
+	// failures older than the reset-period are forgotten
+	last := time.Unix(throttle.Last, 0)
+
+	if throttle.Failures == 0 || now.Sub(last) > config.Login.Reset {
+		return 0
+	}
+
+	// double the delay with every failure
+	delay := time.Duration(float64(config.Login.Delay) * math.Pow(2, float64(throttle.Failures-1)))
+
+	if delay > config.Login.MaxDelay || delay < 0 {
+		delay = config.Login.MaxDelay
+	}
+
+	return max(last.Add(delay).Sub(now), 0)
+}
+
+// subject of the account-throttle of a user
+func accountSubject(uid int) string {
+	return strconv.Itoa(uid)
+}
+
+// checks wether a login-attempt has to wait because of previous failures
+//
+// @returns (response with status 429 if the attempt is throttled)
+func checkLoginThrottle(c *fiber.Ctx, user *UserDB) responseMessage {
+	subjects := [][2]string{{throttleIP, c.IP()}}
+
+	if user != nil {
+		subjects = append(subjects, [2]string{throttleAccount, accountSubject(user.Uid)})
+	}
+
+	return checkThrottles(c, subjects, "too many failed logins")
+}
+
+// checks wether a password-reset-request of the ip-address has to wait because of previous requests
+//
+// @returns (response with status 429 if the request is throttled)
+func checkPasswordResetThrottle(c *fiber.Ctx) responseMessage {
+	return checkThrottles(c, [][2]string{{throttleReset, c.IP()}}, "too many password-reset-requests")
+}
+
+// checks the throttles of the subjects and answers with the message if one of them has to wait
+func checkThrottles(c *fiber.Ctx, subjects [][2]string, message string) responseMessage {
+	var response responseMessage
+
+	now := time.Now()
+
+	for _, subject := range subjects {
+		if throttle, err := store.GetLoginThrottle(subject[0], subject[1]); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't get login-throttle for %s %q: %v", subject[0], subject[1], err)
+
+			return response
+		} else if throttle != nil {
+			if wait := throttle.wait(now); wait > 0 {
+				c.Set(fiber.HeaderRetryAfter, fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+
+				response.Status = fiber.StatusTooManyRequests
+				response.Message = message
+
+				logger.Warn().
+					Str("event", "login_throttled").
+					Str("kind", subject[0]).
+					Str("subject", subject[1]).
+					Str("ip", c.IP()).
+					Int("failures", throttle.Failures).
+					Bool("locked", throttle.Locked > now.Unix()).
+					Dur("wait", wait).
+					Msg("request throttled")
+
+				return response
+			}
+		}
+	}
+
+	return response
+}
+
+// counts a failed login for the ip-address and, if known, the account
+func recordLoginFailure(c *fiber.Ctx, name string, user *UserDB) {
+	now := time.Now()
+
+	event := logger.Warn().
+		Str("event", "login_failed").
+		Str("user", name).
+		Str("ip", c.IP())
+
+	if throttle, err := store.RecordLoginFailure(throttleIP, c.IP(), now, config.Login.Reset, config.Login.IPLockout, config.Login.Lockout); err != nil {
+		logger.Error().Msgf("can't record login-failure for ip %q: %v", c.IP(), err)
+	} else {
+		event = event.Int("ip_failures", throttle.Failures)
+
+		if throttle.Locked > now.Unix() {
+			logger.Warn().
+				Str("event", "ip_locked").
+				Str("ip", c.IP()).
+				Int("failures", throttle.Failures).
+				Time("until", time.Unix(throttle.Locked, 0)).
+				Msg("ip-address locked after failed logins")
+		}
+	}
+
+	if user != nil {
+		event = event.Int("uid", user.Uid)
+
+		if throttle, err := store.RecordLoginFailure(throttleAccount, accountSubject(user.Uid), now, config.Login.Reset, config.Login.AccountLockout, config.Login.Lockout); err != nil {
+			logger.Error().Msgf("can't record login-failure for user with uid = %d: %v", user.Uid, err)
+		} else {
+			event = event.Int("account_failures", throttle.Failures)
+
+			if throttle.Locked > now.Unix() {
+				logger.Warn().
+					Str("event", "account_locked").
+					Int("uid", user.Uid).
+					Str("user", user.Name).
+					Int("failures", throttle.Failures).
+					Time("until", time.Unix(throttle.Locked, 0)).
+					Msg("account locked after failed logins")
+			}
+		}
+	}
+
+	event.Msg("login failed")
+}
+
+// counts a password-reset-request of the ip-address. The requests are counted apart from the failed logins,
+// otherwise anyone knowing the name of a user could lock its account by requesting resets
+func recordPasswordResetRequest(c *fiber.Ctx, name string) {
+	event := logger.Info().
+		Str("event", "password_reset_requested").
+		Str("user", name).
+		Str("ip", c.IP())
+
+	if throttle, err := store.RecordLoginFailure(throttleReset, c.IP(), time.Now(), config.Login.Reset, config.Login.IPLockout, config.Login.Lockout); err != nil {
+		logger.Error().Msgf("can't record password-reset-request for ip %q: %v", c.IP(), err)
+	} else {
+		event = event.Int("ip_requests", throttle.Failures)
+	}
+
+	event.Msg("password-reset requested")
+}
+
+// resets the failure-counter of an account after a successful login
+func clearLoginFailures(user *UserDB) {
+	if _, err := store.ClearLoginFailures(throttleAccount, accountSubject(user.Uid)); err != nil {
+		logger.Error().Msgf("can't clear login-failures for user with uid = %d: %v", user.Uid, err)
+	}
+}
+
+// locked account or ip-address sent to the client
+type LockoutData struct {
+	LoginThrottleDB
+	// name of the user for locked accounts
+	Name string `json:"name,omitempty"`
+}
+
+// handles get-requests for the locked accounts and ip-addresses
+func getLockouts(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	if lockouts, err := store.GetLockouts(time.Now()); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get lockouts from database: %v", err)
+	} else {
+		data := make([]LockoutData, len(lockouts))
+
+		for ii, lockout := range lockouts {
+			data[ii] = LockoutData{LoginThrottleDB: lockout}
+
+			// add the user-name to locked accounts
+			if lockout.Kind == throttleAccount {
+				if uid, err := strconv.Atoi(lockout.Subject); err != nil {
+					logger.Warn().Msgf("invalid account-lockout %q", lockout.Subject)
+				} else if user, err := store.GetUser(uid); err != nil {
+					logger.Error().Msgf("can't get user with uid = %d from database: %v", uid, err)
+				} else if user != nil {
+					data[ii].Name = user.Name
+				}
+			}
+		}
+
+		response.Data = data
+	}
+
+	return response
+}
+
+// handles delete-requests to unlock an account or ip-address
+func deleteLockouts(c *fiber.Ctx) responseMessage {
+	var response responseMessage
+
+	kind := c.Query("kind")
+	subject := c.Query("subject")
+
+	if (kind != throttleAccount && kind != throttleIP && kind != throttleReset) || subject == "" {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "query doesn't include valid kind and subject"
+
+		logger.Info().Msg("query doesn't include valid kind and subject")
+	} else if ok, err := store.ClearLoginFailures(kind, subject); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't unlock %s %q: %v", kind, subject, err)
+	} else if !ok {
+		response.Status = fiber.StatusNotFound
+		response.Message = "no lockout found"
+
+		logger.Info().Msgf("no lockout for %s %q", kind, subject)
+	} else {
+		logger.Info().
+			Str("event", "unlocked").
+			Str("kind", kind).
+			Str("subject", subject).
+			Int("by", getSession(c).Uid).
+			Msg("login-lockout removed")
+
+		response = getLockouts(c)
+	}
+
+	return response
+}
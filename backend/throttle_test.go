@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPasswordResetThrottle(t *testing.T) {
+	setupTestStore(t)
+
+	login := config.Login
+	t.Cleanup(func() { config.Login = login })
+
+	config.Login = LoginConfig{Delay: time.Minute, MaxDelay: time.Hour, AccountLockout: 1, IPLockout: 1, Lockout: time.Hour, Reset: time.Hour}
+
+	if err := store.AddUser("erika", []byte("password"), roleAdmin, nil); err != nil {
+		t.Fatalf("can't add user: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/forgot", handleForgotPassword)
+
+	for _, expected := range []int{fiber.StatusOK, fiber.StatusTooManyRequests} {
+		request := httptest.NewRequest(fiber.MethodPost, "/forgot", strings.NewReader(`{"user": "erika"}`))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		if response, err := app.Test(request, -1); err != nil {
+			t.Fatalf("can't request password-reset: %v", err)
+		} else if response.StatusCode != expected {
+			t.Errorf("password-reset-request returned status %d, expected %d", response.StatusCode, expected)
+		}
+	}
+
+	// the requests mustn't lock the account or the ip-address out of the login
+	user, err := store.GetUserByName("erika")
+	if err != nil || user == nil {
+		t.Fatalf("can't get user: %v", err)
+	}
+
+	for _, subject := range [][2]string{{throttleAccount, accountSubject(user.Uid)}, {throttleIP, "0.0.0.0"}} {
+		if throttle, err := store.GetLoginThrottle(subject[0], subject[1]); err != nil {
+			t.Fatalf("can't get login-throttle: %v", err)
+		} else if throttle != nil {
+			t.Errorf("password-reset-requests were counted as login-failures of %s %q", subject[0], subject[1])
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/skip2/go-qrcode"
+)
+
+// parameters of the time-based one-time-passwords (RFC 6238)
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// accepted deviation of the client-clock in time-steps
+	totpSkew = 1
+	// length of the secret in bytes
+	totpSecretLength = 20
+)
+
+// number of recovery-codes created for a user
+const recoveryCodeCount = 10
+
+// encoding of the totp-secrets as expected by authenticator-apps
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// creates a new random totp-secret
+func newTotpSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// returns the time-step of a point in time
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// calculates the one-time-password of a secret for a time-step
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(secret)
+
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation (RFC 4226, section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, code%1000000), nil
+}
+
+// searches the time-steps around now for one matching the code
+//
+// @returns (matching time-step, error), -1 if no time-step matches
+func matchTotp(secret, code string) (int64, error) {
+	now := totpStep(time.Now())
+
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if expected, err := totpCode(secret, step); err != nil {
+			return -1, err
+		} else if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// creates the provisioning-uri for authenticator-apps
+func totpURI(name, secret string) string {
+	label := url.PathEscape(config.TOTP.Issuer) + ":" + url.PathEscape(name)
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", config.TOTP.Issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// renders a string as qr-code into a png-data-url
+func qrDataURL(content string) (string, error) {
+	if png, err := qrcode.Encode(content, qrcode.Medium, 256); err != nil {
+		return "", err
+	} else {
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+	}
+}
+
+// removes formatting from user-entered codes
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+// hashes a recovery-code for storing it in the database
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// creates new recovery-codes
+//
+// @returns (codes for the user, hashes for the database, error)
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for ii := range codes {
+		if token, err := randomToken(5); err != nil {
+			return nil, nil, err
+		} else {
+			codes[ii] = token[:5] + "-" + token[5:]
+			hashes[ii] = hashRecoveryCode(codes[ii])
+		}
+	}
+
+	return codes, hashes, nil
+}
+
+// checks wether a user still has to set up totp because it is required
+func totpMissing(user *UserDB) bool {
+	return config.TOTP.Required && user.Totp == nil
+}
+
+// checks a one-time-password or recovery-code of a user and invalidates it
+func verifySecondFactor(user *UserDB, code string) (bool, error) {
+	if user.Totp == nil {
+		return false, nil
+	}
+
+	code = normalizeCode(code)
+
+	if len(code) == totpDigits {
+		if step, err := matchTotp(*user.Totp, code); err != nil || step < 0 {
+			return false, err
+		} else {
+			return store.UseTotpStep(user.Uid, step)
+		}
+	} else {
+		return store.UseRecoveryCode(user.Uid, hashRecoveryCode(code))
+	}
+}
+
+// body of requests containing a second factor
+type TotpBody struct {
+	Code string `json:"code"`
+}
+
+// state of the totp of a user
+type TotpStatus struct {
+	Enabled       bool `json:"enabled"`
+	Required      bool `json:"required"`
+	RecoveryCodes int  `json:"recovery_codes"`
+}
+
+// data for setting up an authenticator-app
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QR     string `json:"qr"`
+}
+
+// new recovery-codes, shown to the user only once
+type TotpRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// starts the second login-step by storing the login-challenge in a cookie
+func startLoginChallenge(c *fiber.Ctx, user *UserDB) responseMessage {
+	var response responseMessage
+
+	if challenge, err := config.signLoginChallengeJWT(user.Uid, user.Tid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("login-challenge creation failed: %v", err)
+	} else {
+		c.Cookie(&fiber.Cookie{
+			Name:     "login",
+			Value:    challenge,
+			HTTPOnly: true,
+			SameSite: "strict",
+			MaxAge:   int(loginChallengeExpiration.Seconds()),
+		})
+
+		response.Data = UserLogin{
+			LoggedIn: false,
+			Totp:     true,
+		}
+
+		logger.Debug().Msgf("user with uid = %d entered the password, waiting for second factor", user.Uid)
+	}
+
+	return response
+}
+
+// removes the login-challenge-cookie from a request
+func removeLoginChallengeCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "login",
+		Value:    "",
+		HTTPOnly: true,
+		SameSite: "strict",
+		Expires:  time.Unix(0, 0),
+	})
+}
+
+// handles the second login-step with a one-time-password or recovery-code
+func handleLoginTotp(c *fiber.Ctx) error {
+	logger.Debug().Msgf("HTTP %s request: %q", c.Method(), c.OriginalURL())
+
+	var response responseMessage
+	var payload LoginChallengePayload
+
+	body := TotpBody{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "can't parse message-body"
+
+		logger.Warn().Msgf("can't parse totp-login-body: %v", err)
+	} else if token, err := jwt.ParseWithClaims(c.Cookies("login"), &payload, config.verificationKey, jwt.WithAudience(loginChallengeAudience)); err != nil || !token.Valid {
+		response.Status = fiber.StatusUnauthorized
+		response.Message = "login expired"
+
+		logger.Info().Msgf("invalid login-challenge: %v", err)
+	} else if user, err := store.GetUser(payload.Uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get user with uid = %d from database: %v", payload.Uid, err)
+	} else if user == nil || user.Tid != payload.Tid {
+		response.Status = fiber.StatusUnauthorized
+		response.Message = "login expired"
+
+		logger.Info().Msgf("login-challenge for user with uid = %d is no longer valid", payload.Uid)
+	} else if response = checkLoginThrottle(c, user); response.Status >= 400 {
+		// too many failed logins, the client has to wait
+	} else if ok, err := verifySecondFactor(user, body.Code); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't verify second factor for user with uid = %d: %v", user.Uid, err)
+	} else if !ok {
+		response.Status = fiber.StatusUnauthorized
+		response.Message = "invalid code"
+
+		logger.Info().Msgf("user with uid = %d entered an invalid second factor", user.Uid)
+
+		recordLoginFailure(c, user.Name, user)
+	} else {
+		removeLoginChallengeCookie(c)
+		clearLoginFailures(user)
+
+		response = startSession(c, user)
+	}
+
+	return response.send(c)
+}
+
+// returns the user of the current session
+func getSessionUser(c *fiber.Ctx) (*UserDB, responseMessage) {
+	var response responseMessage
+
+	uid := getSession(c).Uid
+
+	user, err := store.GetUser(uid)
+
+	if err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't get user with uid = %d from database: %v", uid, err)
+	} else if user == nil {
+		response.Status = fiber.StatusUnauthorized
+
+		logger.Info().Msgf("user with uid = %d doesn't exist", uid)
+	}
+
+	return user, response
+}
+
+// handles get-requests for the totp-state of the user
+func getUserTotp(c *fiber.Ctx) responseMessage {
+	user, response := getSessionUser(c)
+
+	if user != nil {
+		if count, err := store.CountRecoveryCodes(user.Uid); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't count recovery-codes of user with uid = %d: %v", user.Uid, err)
+		} else {
+			response.Data = TotpStatus{
+				Enabled:       user.Totp != nil,
+				Required:      config.TOTP.Required,
+				RecoveryCodes: count,
+			}
+		}
+	}
+
+	return response
+}
+
+// handles post-requests to start the totp-enrollment
+func postUserTotp(c *fiber.Ctx) responseMessage {
+	user, response := getSessionUser(c)
+
+	if user == nil {
+		return response
+	}
+
+	if user.Totp != nil {
+		response.Status = fiber.StatusConflict
+		response.Message = "totp is already enabled"
+
+		logger.Info().Msgf("user with uid = %d tried to enroll totp twice", user.Uid)
+	} else if secret, err := newTotpSecret(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create totp-secret: %v", err)
+	} else if err := store.SetTotpEnrollment(user.Uid, &secret); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't store totp-enrollment for user with uid = %d: %v", user.Uid, err)
+	} else {
+		uri := totpURI(user.Name, secret)
+
+		if qr, err := qrDataURL(uri); err != nil {
+			response.Status = fiber.StatusInternalServerError
+
+			logger.Error().Msgf("can't create totp-qr-code: %v", err)
+		} else {
+			response.Data = TotpEnrollment{
+				Secret: secret,
+				URI:    uri,
+				QR:     qr,
+			}
+
+			logger.Debug().Msgf("started totp-enrollment for user with uid = %d", user.Uid)
+		}
+	}
+
+	return response
+}
+
+// handles patch-requests to finish the totp-enrollment with a first code
+func patchUserTotp(c *fiber.Ctx) responseMessage {
+	user, response := getSessionUser(c)
+
+	if user == nil {
+		return response
+	}
+
+	body := TotpBody{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ code string }"`)
+	} else if user.Enrollment == nil {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "no totp-enrollment started"
+
+		logger.Info().Msgf("user with uid = %d has no totp-enrollment", user.Uid)
+	} else if step, err := matchTotp(*user.Enrollment, normalizeCode(body.Code)); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't verify totp-code: %v", err)
+	} else if step < 0 {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid code"
+
+		logger.Info().Msgf("user with uid = %d entered an invalid code for the totp-enrollment", user.Uid)
+	} else if codes, hashes, err := newRecoveryCodes(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create recovery-codes: %v", err)
+	} else if err := store.EnableTotp(user.Uid, *user.Enrollment, hashes); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't enable totp for user with uid = %d: %v", user.Uid, err)
+	} else if _, err := store.UseTotpStep(user.Uid, step); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't store totp-step for user with uid = %d: %v", user.Uid, err)
+	} else {
+		response.Data = TotpRecoveryCodes{
+			RecoveryCodes: codes,
+		}
+
+		logger.Info().Msgf("enabled totp for user with uid = %d", user.Uid)
+	}
+
+	return response
+}
+
+// handles delete-requests to disable totp, confirmed by a second factor
+func deleteUserTotp(c *fiber.Ctx) responseMessage {
+	user, response := getSessionUser(c)
+
+	if user == nil {
+		return response
+	}
+
+	body := TotpBody{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ code string }"`)
+	} else if config.TOTP.Required {
+		response.Status = fiber.StatusForbidden
+		response.Message = "totp is required"
+
+		logger.Info().Msgf("user with uid = %d tried to disable required totp", user.Uid)
+	} else if ok, err := verifySecondFactor(user, body.Code); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't verify second factor for user with uid = %d: %v", user.Uid, err)
+	} else if !ok {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid code"
+
+		logger.Info().Msgf("user with uid = %d entered an invalid second factor", user.Uid)
+	} else if err := store.DisableTotp(user.Uid); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't disable totp for user with uid = %d: %v", user.Uid, err)
+	} else {
+		logger.Info().Msgf("disabled totp for user with uid = %d", user.Uid)
+
+		response = getUserTotp(c)
+	}
+
+	return response
+}
+
+// handles post-requests to replace the recovery-codes, confirmed by a second factor
+func postUserTotpRecovery(c *fiber.Ctx) responseMessage {
+	user, response := getSessionUser(c)
+
+	if user == nil {
+		return response
+	}
+
+	body := TotpBody{}
+
+	if err := c.BodyParser(&body); err != nil {
+		response.Status = fiber.StatusBadRequest
+
+		logger.Warn().Msg(`body can't be parsed as "struct{ code string }"`)
+	} else if ok, err := verifySecondFactor(user, body.Code); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't verify second factor for user with uid = %d: %v", user.Uid, err)
+	} else if !ok {
+		response.Status = fiber.StatusBadRequest
+		response.Message = "invalid code"
+
+		logger.Info().Msgf("user with uid = %d entered an invalid second factor", user.Uid)
+	} else if codes, hashes, err := newRecoveryCodes(); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't create recovery-codes: %v", err)
+	} else if err := store.SetRecoveryCodes(user.Uid, hashes); err != nil {
+		response.Status = fiber.StatusInternalServerError
+
+		logger.Error().Msgf("can't store recovery-codes for user with uid = %d: %v", user.Uid, err)
+	} else {
+		response.Data = TotpRecoveryCodes{
+			RecoveryCodes: codes,
+		}
+
+		logger.Info().Msgf("replaced recovery-codes for user with uid = %d", user.Uid)
+	}
+
+	return response
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+	// test-vectors of RFC 6238, appendix B, for sha1 with the ascii-secret "12345678901234567890", shortened to six digits
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		if code, err := totpCode(secret, totpStep(time.Unix(test.time, 0))); err != nil {
+			t.Errorf("can't calculate code for %d: %v", test.time, err)
+		} else if code != test.code {
+			t.Errorf("code for %d is %q, expected %q", test.time, code, test.code)
+		}
+	}
+
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("invalid secret was accepted")
+	}
+}
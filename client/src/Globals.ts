@@ -19,13 +19,82 @@ void (async () => {
 	}
 })();
 
+export interface ElementType {
+	id: string;
+	name: string;
+	gender: "m" | "f" | "n";
+	description: string;
+	location?: string;
+	price: number;
+	capacity?: string;
+	prefixes: string[];
+	count: number;
+}
+
+export const element_catalogue = ref<ElementType[]>([]);
+
+void (async () => {
+	const response = await api_call<ElementType[]>("GET", "elements/catalogue");
+
+	if (response.ok) {
+		element_catalogue.value = await response.json();
+	}
+})();
+
+export interface LayoutSlot {
+	mid?: string;
+	x: number;
+	y: number;
+	width: number;
+	height: number;
+}
+
+export interface LayoutSection {
+	id: string;
+	type: string;
+	class?: string;
+	elements: LayoutSlot[];
+	unavailable: LayoutSlot[];
+}
+
+export interface Layout {
+	width: number;
+	height: number;
+	background?: string;
+	sections: LayoutSection[];
+}
+
+export const layout = ref<Layout>();
+
+void (async () => {
+	const response = await api_call<Layout>("GET", "layout");
+
+	if (response.ok) {
+		layout.value = await response.json();
+	}
+})();
+
+export type Role = "admin" | "treasurer" | "editor" | "viewer";
+
+export const roles: Record<Role, string> = {
+	admin: "Administrator",
+	treasurer: "Kassenwart",
+	editor: "Bearbeiter",
+	viewer: "Betrachter"
+};
+
 export interface User {
 	uid: number;
 	name: string;
+	role: Role;
+	totp?: boolean;
+	email?: string;
 }
 
 export interface UserLogin extends User {
 	logged_in: boolean;
+	totp?: boolean;
+	totp_required?: boolean;
 }
 
 export const user = ref<UserLogin>();
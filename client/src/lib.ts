@@ -22,6 +22,7 @@ export enum HTTPStatus {
 	Permanent = 308,
 	BadRequest = 400,
 	Unauthorized = 401,
+	PaymentRequired = 402,
 	Forbidden = 403,
 	NotFound = 404,
 	MethodNotAllowed = 405,
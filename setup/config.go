@@ -11,9 +11,83 @@ import (
 
 var CONFIG_PATH = "../backend/config.yaml"
 
+// key of the jwt-keyring in the config-file
+type JWTKeyYaml struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+	// time of the retirement in RFC 3339, empty for active keys
+	Retired string `yaml:"retired,omitempty"`
+}
+
+// type of elements in the catalogue of the config-file
+type ElementTypeYaml struct {
+	ID   string `yaml:"id"`
+	Name string `yaml:"name"`
+	// grammatical gender of the name: "m", "f" or "n"
+	Gender      string  `yaml:"gender"`
+	Description string  `yaml:"description"`
+	Location    string  `yaml:"location,omitempty"`
+	Price       float64 `yaml:"price"`
+	Capacity    string  `yaml:"capacity,omitempty"`
+}
+
+// row of elements in a section of the layout
+type LayoutRowYaml struct {
+	// prefix of the element-ids, the slots are numbered from 1
+	Prefix  string `yaml:"prefix"`
+	Columns int    `yaml:"columns"`
+	// number of empty columns before the first slot
+	Offset int `yaml:"offset,omitempty"`
+	// numbers of the slots without an element
+	Unavailable []int `yaml:"unavailable,omitempty"`
+}
+
+// section of the layout with elements of a single type, e.g. a roof
+type LayoutSectionYaml struct {
+	ID   string `yaml:"id"`
+	Type string `yaml:"type"`
+	// css-class of the elements in the client
+	Class string  `yaml:"class,omitempty"`
+	X     float64 `yaml:"x"`
+	Y     float64 `yaml:"y"`
+	// "portrait" or "landscape", the element-size is given in portrait
+	Orientation   string          `yaml:"orientation"`
+	ElementWidth  float64         `yaml:"element_width"`
+	ElementHeight float64         `yaml:"element_height"`
+	Gap           float64         `yaml:"gap"`
+	Rows          []LayoutRowYaml `yaml:"rows"`
+}
+
+// layout of the elements in the config-file
+type LayoutYaml struct {
+	Width  float64 `yaml:"width"`
+	Height float64 `yaml:"height"`
+	// image below the elements, relative to the client
+	Background string              `yaml:"background,omitempty"`
+	Sections   []LayoutSectionYaml `yaml:"sections"`
+}
+
+// issuer of the donation-receipts in the config-file
+type ReceiptYaml struct {
+	// path of a text-template for the receipts, the built-in one is used if empty
+	Template string `yaml:"template,omitempty"`
+	Name     string `yaml:"name"`
+	Address  string `yaml:"address"`
+	// tax-office, tax-number and date of the exemption-notice
+	TaxOffice  string `yaml:"tax_office"`
+	TaxNumber  string `yaml:"tax_number"`
+	NoticeDate string `yaml:"notice_date"`
+	// tax-privileged purpose, e.g. "des Umweltschutzes"
+	Purpose   string `yaml:"purpose"`
+	Place     string `yaml:"place"`
+	Signatory string `yaml:"signatory"`
+}
+
 type ConfigYaml struct {
 	LogLevel string `yaml:"log_level"`
 	Database struct {
+		Driver   string `yaml:"driver"`
+		File     string `yaml:"file"`
 		Host     string `yaml:"host"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
@@ -24,32 +98,76 @@ type ConfigYaml struct {
 		Purge      string `yaml:"purge"`
 	} `yaml:"cache"`
 	ClientSession struct {
-		JwtSignature string `yaml:"jwt_signature"`
-		Expire       string `yaml:"expire"`
+		JwtSignature string       `yaml:"jwt_signature,omitempty"`
+		Expire       string       `yaml:"expire"`
+		KeyGrace     string       `yaml:"key_grace"`
+		Keys         []JWTKeyYaml `yaml:"keys"`
 	} `yaml:"client_session"`
 	Server struct {
-		Port int `yaml:"port"`
+		Port        int    `yaml:"port"`
+		URL         string `yaml:"url"`
+		ProxyHeader string `yaml:"proxy_header"`
 	} `yaml:"server"`
 	Reservation struct {
-		Expiration string `yaml:"expiration"`
+		Expiration             string `yaml:"expiration"`
+		ConfirmationExpiration string `yaml:"confirmation_expiration"`
 	} `yaml:"reservation"`
-	Mail struct {
-		Server    string `yaml:"server"`
-		Port      int    `yaml:"port"`
-		User      string `yaml:"user"`
-		Password  string `yaml:"password"`
-		Templates struct {
+	Payment struct {
+		Beneficiary string `yaml:"beneficiary"`
+		IBAN        string `yaml:"iban"`
+		BIC         string `yaml:"bic"`
+	} `yaml:"payment"`
+	Receipt ReceiptYaml `yaml:"receipt"`
+	Mail    struct {
+		Transport  string `yaml:"transport"`
+		Server     string `yaml:"server"`
+		Port       int    `yaml:"port"`
+		Encryption string `yaml:"encryption"`
+		User       string `yaml:"user"`
+		Password   string `yaml:"password"`
+		Sendmail   string `yaml:"sendmail"`
+		Directory  string `yaml:"directory"`
+		Templates  struct {
 			ReservationSubject string `yaml:"reservation_subject"`
 			CertificateSubject string `yaml:"certificate_subject"`
 		} `yaml:"subject_templates"`
+		Outbox struct {
+			Interval    string `yaml:"interval"`
+			Backoff     string `yaml:"backoff"`
+			MaxBackoff  string `yaml:"max_backoff"`
+			MaxAttempts int    `yaml:"max_attempts"`
+		} `yaml:"outbox"`
 	} `yaml:"mail"`
-	ValidateElements struct {
-		Regex         string `yaml:"regex"`
-		ValidElements map[string]struct {
-			From int `yaml:"from"`
-			To   int `yaml:"to"`
-		} `yaml:"valid_elements"`
-	} `yaml:"validate_elements"`
+	Certificate struct {
+		Renderer string `yaml:"renderer"`
+		Inkscape string `yaml:"inkscape"`
+		Font     string `yaml:"font"`
+		Fonts    []struct {
+			Family string `yaml:"family"`
+			Bold   bool   `yaml:"bold"`
+			Italic bool   `yaml:"italic"`
+			File   string `yaml:"file"`
+		} `yaml:"fonts"`
+		Archive string `yaml:"archive"`
+		Workers int    `yaml:"workers"`
+	} `yaml:"certificate"`
+	PasswordReset struct {
+		Expiration string `yaml:"expiration"`
+	} `yaml:"password_reset"`
+	Login struct {
+		Delay          string `yaml:"delay"`
+		MaxDelay       string `yaml:"max_delay"`
+		AccountLockout int    `yaml:"account_lockout"`
+		IPLockout      int    `yaml:"ip_lockout"`
+		Lockout        string `yaml:"lockout"`
+		Reset          string `yaml:"reset"`
+	} `yaml:"login"`
+	TOTP struct {
+		Issuer   string `yaml:"issuer"`
+		Required bool   `yaml:"required"`
+	} `yaml:"totp"`
+	Elements []ElementTypeYaml `yaml:"elements"`
+	Layout   LayoutYaml        `yaml:"layout"`
 }
 
 type CacheConfig struct {
@@ -77,6 +195,24 @@ func loadConfig() ConfigYaml {
 		os.Exit(1)
 	}
 
+	// default to mysql for older configs without a database-driver
+	if config.Database.Driver == "" {
+		config.Database.Driver = "mysql"
+	}
+
+	// default to the formerly hardcoded smtp with tls for older configs
+	if config.Mail.Transport == "" {
+		config.Mail.Transport = "smtp"
+	}
+
+	if config.Mail.Transport == "smtp" && config.Mail.Encryption == "" {
+		config.Mail.Encryption = "tls"
+	}
+
+	if config.TOTP.Issuer == "" {
+		config.TOTP.Issuer = "PV-Pate"
+	}
+
 	return config
 }
 
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// id of the key from "client_session.jwt_signature" in older configs
+const legacyJWTKeyID = "legacy"
+
+// duration retired keys are still accepted by the backend
+func jwtKeyGrace() (time.Duration, error) {
+	grace := config.ClientSession.KeyGrace
+
+	// the backend defaults to the session-expiration
+	if grace == "" {
+		grace = config.ClientSession.Expire
+	}
+
+	return time.ParseDuration(grace)
+}
+
+// adds a new jwt-key, retires the active ones and removes keys whose grace-period has ended
+func rotateJWTKey() error {
+	now := time.Now()
+
+	grace, err := jwtKeyGrace()
+	if err != nil {
+		return fmt.Errorf(`can't parse "client_session.key_grace": %v`, err)
+	}
+
+	keys := config.ClientSession.Keys
+
+	// move the signature of older configs into the keyring
+	if config.ClientSession.JwtSignature != "" {
+		keys = append([]JWTKeyYaml{{ID: legacyJWTKeyID, Secret: config.ClientSession.JwtSignature}}, keys...)
+
+		config.ClientSession.JwtSignature = ""
+	}
+
+	rotatedKeys := []JWTKeyYaml{}
+
+	for _, key := range keys {
+		if key.Retired == "" {
+			key.Retired = now.Format(time.RFC3339)
+
+			fmt.Printf("retired jwt-key %q\n", key.ID)
+		} else if retired, err := time.Parse(time.RFC3339, key.Retired); err != nil {
+			return fmt.Errorf("can't parse retirement of jwt-key %q: %v", key.ID, err)
+		} else if now.After(retired.Add(grace)) {
+			fmt.Printf("removed jwt-key %q\n", key.ID)
+
+			continue
+		}
+
+		rotatedKeys = append(rotatedKeys, key)
+	}
+
+	newKey := JWTKeyYaml{
+		ID:     now.Format("20060102-150405"),
+		Secret: createPassword(100),
+	}
+
+	// make the key-id unique, if there were several rotations in the same second
+	for ii := 2; slices.ContainsFunc(rotatedKeys, func(key JWTKeyYaml) bool { return key.ID == newKey.ID }); ii++ {
+		newKey.ID = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), ii)
+	}
+
+	config.ClientSession.Keys = append(rotatedKeys, newKey)
+
+	fmt.Printf("added jwt-key %q\n", newKey.ID)
+
+	// write the modified config-file
+	writeConfig()
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// a single schema-migration
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// regex to match the file-names of the migrations
+var migrationRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// directory with the migrations of the configured database-driver
+func migrationsDir() string {
+	return path.Join(path.Dir(CONFIG_PATH), "migrations", config.Database.Driver)
+}
+
+// loads the migrations ordered by their version
+func loadMigrations() ([]Migration, error) {
+	dir := migrationsDir()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := map[int]*Migration{}
+
+	for _, entry := range entries {
+		results := migrationRegex.FindStringSubmatch(entry.Name())
+
+		if results == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(results[1])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := os.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: results[2]}
+			migrations[version] = migration
+		} else if migration.Name != results[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, results[2])
+		}
+
+		if results[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+
+	for _, migration := range migrations {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up-script", migration.Version, migration.Name)
+		}
+
+		result = append(result, *migration)
+	}
+
+	slices.SortFunc(result, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	return result, nil
+}
+
+// creates the tracking-table and returns the applied migration-versions
+func appliedMigrations(db *sql.DB) (map[int]bool, error) {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := map[int]bool{}
+
+	for rows.Next() {
+		var version int
+
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// runs a migration-script and updates the tracking-table in a single transaction.
+// mysql commits every ddl-statement implicitly, so there the transaction doesn't make the migration atomic:
+// a script that fails halfway is left partly applied and isn't recorded. Because of that, the mysql-scripts
+// hold either a single ddl-statement or only idempotent statements before the last one, so they can simply be run again
+func runMigration(db *sql.DB, script, record string, args ...any) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	} else if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applies all pending migrations
+func migrate(db *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("applying schema-migrations:")
+
+	count := 0
+
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		fmt.Printf("\t%04d_%s\n", migration.Version, migration.Name)
+
+		if err := runMigration(db, migration.Up, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("can't apply migration %04d_%s: %v", migration.Version, migration.Name, err)
+		}
+
+		count++
+	}
+
+	fmt.Printf("applied %d migrations\n", count)
+
+	return nil
+}
+
+// prints the applied and pending migrations
+func status(db *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+
+	for _, migration := range migrations {
+		state := "applied"
+
+		if !applied[migration.Version] {
+			state = "pending"
+			pending++
+		}
+
+		fmt.Printf("%04d_%-30s %s\n", migration.Version, migration.Name, state)
+	}
+
+	fmt.Printf("%d of %d migrations pending\n", pending, len(migrations))
+
+	return nil
+}
+
+// reverts the last applied migrations
+func rollback(db *sql.DB, steps int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("reverting schema-migrations:")
+
+	for ii := len(migrations) - 1; ii >= 0 && steps > 0; ii-- {
+		migration := migrations[ii]
+
+		if !applied[migration.Version] {
+			continue
+		}
+
+		if migration.Down == "" {
+			return fmt.Errorf("migration %04d_%s has no down-script", migration.Version, migration.Name)
+		}
+
+		fmt.Printf("\t%04d_%s\n", migration.Version, migration.Name)
+
+		if err := runMigration(db, migration.Down, "DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+			return fmt.Errorf("can't revert migration %04d_%s: %v", migration.Version, migration.Name, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"os"
-	"regexp"
-	"strings"
+	"path"
+	"strconv"
 
 	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
 )
 
 func createPassword(l int) string {
@@ -28,64 +29,46 @@ func exit(e error) {
 	os.Exit(1)
 }
 
-func main() {
+// connects to the database from the config
+func openDatabase() (*sql.DB, error) {
 	fmt.Println("connecting to database")
 
-	// connect to the database
-	sqlConfig := mysql.Config{
-		AllowNativePasswords: true,
-		Net:                  "tcp",
-		User:                 config.Database.User,
-		Passwd:               config.Database.Password,
-		Addr:                 config.Database.Host,
-		DBName:               config.Database.Database,
-	}
+	switch config.Database.Driver {
+	case "mysql":
+		sqlConfig := mysql.Config{
+			AllowNativePasswords: true,
+			MultiStatements:      true,
+			Net:                  "tcp",
+			User:                 config.Database.User,
+			Passwd:               config.Database.Password,
+			Addr:                 config.Database.Host,
+			DBName:               config.Database.Database,
+		}
 
-	db, err := sql.Open("mysql", sqlConfig.FormatDSN())
-	if err != nil {
-		exit(err)
+		return sql.Open("mysql", sqlConfig.FormatDSN())
+	case "sqlite":
+		// the database-file is relative to the backend
+		return sql.Open("sqlite", path.Join(path.Dir(CONFIG_PATH), config.Database.File))
+	default:
+		return nil, fmt.Errorf("unknown database-driver %q", config.Database.Driver)
 	}
+}
 
-	// load the sql-script
-	fmt.Println(`reading "setup.sql"`)
-	var sqlScriptCommands []byte
-	if c, err := os.ReadFile("setup.sql"); err != nil {
+// migrates the database and creates the admin-user
+func setupDatabase(db *sql.DB) {
+	if err := migrate(db); err != nil {
 		exit(err)
-	} else {
-		sqlScriptCommands = c
 	}
 
-	// read the currently availabe tables
-	fmt.Println("reading available tables in database")
-	if rows, err := db.Query("SHOW TABLES"); err != nil {
+	// check wether there is already an admin-user
+	var admins int
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE name = 'admin'").Scan(&admins); err != nil {
 		exit(err)
-	} else {
-		defer rows.Close()
-
-		fmt.Println("checking for already existing tables in database")
-		for rows.Next() {
-			var name string
-
-			if err := rows.Scan(&name); err != nil {
-				exit(err)
-			} else {
-				// check wether for the table there exists a create command
-
-				if match, err := regexp.Match(fmt.Sprintf(`(?i)^create table %s`, name), sqlScriptCommands); err != nil {
-					exit(err)
-				} else {
-					if match {
-						exit(fmt.Errorf("can't setup databases: table %q already exists", name))
-					}
-				}
-			}
-		}
-	}
+	} else if admins > 0 {
+		fmt.Println(`user "admin" already exists`)
 
-	// everything is good (so far), create the tables
-	fmt.Println("Creating the individual tables:")
-	for _, cmd := range strings.Split(string(sqlScriptCommands), "\n") {
-		db.Exec(cmd)
+		return
 	}
 
 	fmt.Println("Creating admin-password:")
@@ -101,7 +84,7 @@ func main() {
 		fmt.Println("\thashed password")
 
 		// create an admin-user
-		if _, err := db.Exec("INSERT INTO users (name, password) VALUES ('admin', ?)", passwordHash); err != nil {
+		if _, err := db.Exec("INSERT INTO users (name, password, role) VALUES ('admin', ?, 'admin')", passwordHash); err != nil {
 			exit(err)
 		}
 
@@ -110,9 +93,65 @@ func main() {
 
 	fmt.Printf("created user \"admin\" with password %s\n", password)
 
-	// create a jwt-signature
-	config.ClientSession.JwtSignature = createPassword(100)
+	// create a key for signing the jwts
+	if err := rotateJWTKey(); err != nil {
+		exit(err)
+	}
+}
+
+const usage = `usage: setup [command]
+
+commands:
+  (none)            migrate the database and create the admin-user
+  migrate           apply all pending schema-migrations
+  status            show the applied and pending schema-migrations
+  rollback [steps]  revert the last schema-migrations (default: 1)
+  rotate-jwt-key    add a new jwt-key and retire the old ones (restart the backend afterwards)`
+
+func main() {
+	// the key-rotation only modifies the config-file
+	if len(os.Args) > 1 && os.Args[1] == "rotate-jwt-key" {
+		if err := rotateJWTKey(); err != nil {
+			exit(err)
+		}
+
+		return
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		exit(err)
+	}
 
-	// write the modified config-file
-	writeConfig()
+	defer db.Close()
+
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "":
+		setupDatabase(db)
+	case "migrate":
+		err = migrate(db)
+	case "status":
+		err = status(db)
+	case "rollback":
+		steps := 1
+
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps < 1 {
+				exit(fmt.Errorf("invalid number of steps: %q", os.Args[2]))
+			}
+		}
+
+		err = rollback(db, steps)
+	default:
+		exit(fmt.Errorf("unknown command %q\n\n%s", command, usage))
+	}
+
+	if err != nil {
+		exit(err)
+	}
 }